@@ -0,0 +1,268 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/locale"
+	"github.com/google/uuid"
+)
+
+const frontMatterDelimiter = "---"
+
+var (
+	// headerFields are the task fields of the front matter, the description follows the header.
+	headerFields = []string{"subject", "due", "tags", "recurrence"}
+	setFieldList = []string{"subject", "due", "tags", "recurrence", "description"}
+)
+
+type EditTaskCmd struct {
+	ID  uuid.UUID `arg:"" required:"" help:"ID of task to edit."`
+	Set []string  `sep:"none" help:"Set a field (subject, due, tags, recurrence or description) without the editor, e.g. due=2026-12-24." placeholder:"FIELD=VALUE"`
+}
+
+func (cmd *EditTaskCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		task, found, err := storage.Task(ctx, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.ID.String()}))
+		}
+
+		data := entity.TaskData{
+			DueDate:     task.DueDate,
+			Subject:     task.Subject,
+			Description: task.Description,
+			Tags:        task.Tags,
+			Version:     task.Version, // detects concurrent updates
+			Recurrence:  task.Recurrence,
+		}
+
+		if len(cmd.Set) > 0 {
+			err = setFields(ctx, &data, cmd.Set)
+		} else {
+			data, err = editTaskData(ctx, data)
+		}
+		if err != nil {
+			return err
+		}
+
+		if unchanged(task, data) {
+			if globals.Output != outputTable {
+				return writeTask(ctx, w, globals.Output, task)
+			}
+			fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_unchanged", map[string]string{"id": cmd.ID.String()}))
+
+			return nil
+		}
+
+		task, found, err = storage.UpdateTask(ctx, cmd.ID, data)
+		var conflict entity.ConflictError
+		if errors.As(err, &conflict) {
+			return errors.New(locale.Translate(ctx, "conflict_task_update"))
+		}
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.ID.String()}))
+		}
+
+		if globals.Output != outputTable {
+			return writeTask(ctx, w, globals.Output, task)
+		}
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_updated", map[string]string{"id": cmd.ID.String()}))
+
+		return nil
+	})
+}
+
+func unchanged(task entity.Task, data entity.TaskData) bool {
+	return task.DueDate.Equal(data.DueDate) &&
+		task.Subject == data.Subject &&
+		task.Description == data.Description &&
+		slices.Equal(task.Tags, data.Tags) &&
+		task.Recurrence.String() == data.Recurrence.String()
+}
+
+// setFields sets the field=value assignments.
+func setFields(ctx context.Context, data *entity.TaskData, assignments []string) error {
+	for _, assignment := range assignments {
+		field, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return unknownField(ctx, assignment, setFieldList)
+		}
+
+		err := setField(ctx, data, strings.TrimSpace(field), value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setField(ctx context.Context, data *entity.TaskData, field, value string) error {
+	switch field {
+	case "subject":
+		subject := strings.TrimSpace(value)
+		if length := utf8.RuneCountInString(subject); length < entity.SubjectMinLength || length > entity.SubjectMaxLength {
+			return invalidField(ctx, field, value)
+		}
+		data.Subject = subject
+	case "due":
+		dueDate := time.Time{}
+		if strings.TrimSpace(value) != "" {
+			var err error
+			dueDate, err = time.Parse(time.DateOnly, strings.TrimSpace(value))
+			if err != nil {
+				return invalidField(ctx, field, value)
+			}
+		}
+		data.DueDate = dueDate
+	case "tags":
+		tags, err := entity.ParseTags(value)
+		if err != nil {
+			return invalidField(ctx, field, value)
+		}
+		data.Tags = tags
+	case "recurrence":
+		recurrence, err := entity.ParseRecurrence(value)
+		if err != nil {
+			return invalidField(ctx, field, value)
+		}
+		data.Recurrence = recurrence
+	case "description":
+		data.Description = value
+	default:
+		return unknownField(ctx, field, setFieldList)
+	}
+
+	return nil
+}
+
+func invalidField(ctx context.Context, field, value string) error {
+	return errors.New(locale.TranslateData(ctx, "invalid_task_field", map[string]string{"field": field, "value": value}))
+}
+
+func unknownField(ctx context.Context, field string, fields []string) error {
+	return errors.New(locale.TranslateData(ctx, "unknown_task_field", map[string]string{
+		"field":  field,
+		"fields": strings.Join(fields, ", "),
+	}))
+}
+
+// editTaskData opens the task file in the editor, it keeps the file if the changes are invalid.
+func editTaskData(ctx context.Context, data entity.TaskData) (entity.TaskData, error) {
+	file, err := os.CreateTemp("", "task-*.md")
+	if err != nil {
+		return data, err
+	}
+
+	content := renderTaskFile(data)
+	_, err = file.WriteString(content)
+	err = errors.Join(err, file.Close())
+	if err == nil {
+		err = runEditor(ctx, file.Name())
+	}
+
+	var edited []byte
+	if err == nil {
+		edited, err = os.ReadFile(file.Name())
+	}
+
+	if err != nil || string(edited) == content {
+		return data, errors.Join(err, os.Remove(file.Name()))
+	}
+
+	err = parseTaskFile(ctx, &data, string(edited))
+	if err != nil {
+		return data, fmt.Errorf("%w %s", err, locale.TranslateData(ctx, "task_edit_kept", map[string]string{"path": file.Name()}))
+	}
+
+	return data, os.Remove(file.Name())
+}
+
+// runEditor runs $VISUAL or $EDITOR (vi by default) by the shell, like git it accepts editor arguments, e.g. "code --wait".
+func runEditor(ctx context.Context, path string) error {
+	editor := cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+	cmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// renderTaskFile returns the Markdown description with a front matter header of the other fields.
+func renderTaskFile(data entity.TaskData) string {
+	dueDate := ""
+	if !data.DueDate.IsZero() {
+		dueDate = data.DueDate.Format(time.DateOnly)
+	}
+
+	var b strings.Builder
+	b.WriteString(frontMatterDelimiter + "\n")
+	for _, field := range [][2]string{
+		{"subject", data.Subject},
+		{"due", dueDate},
+		{"tags", strings.Join(data.Tags, ", ")},
+		{"recurrence", data.Recurrence.String()},
+	} {
+		b.WriteString(strings.TrimSpace(field[0]+": "+field[1]) + "\n")
+	}
+	b.WriteString(frontMatterDelimiter + "\n\n")
+	b.WriteString(data.Description + "\n")
+
+	return b.String()
+}
+
+func parseTaskFile(ctx context.Context, data *entity.TaskData, content string) error {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontMatterDelimiter {
+		return errors.New(locale.Translate(ctx, "missing_front_matter"))
+	}
+
+	end := slices.IndexFunc(lines[1:], func(line string) bool {
+		return strings.TrimSpace(line) == frontMatterDelimiter
+	})
+	if end == -1 {
+		return errors.New(locale.Translate(ctx, "missing_front_matter"))
+	}
+	end++ // index of lines
+
+	for _, line := range lines[1:end] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		field = strings.TrimSpace(field)
+		if !slices.Contains(headerFields, field) {
+			return unknownField(ctx, field, headerFields)
+		}
+
+		err := setField(ctx, data, field, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	data.Description = strings.Trim(strings.Join(lines[end+1:], "\n"), "\n")
+
+	return nil
+}
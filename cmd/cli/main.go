@@ -1,107 +1,233 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
-	"github.com/charmbracelet/glamour"
 	"github.com/dgf/go-ssr-x/entity"
 	"github.com/dgf/go-ssr-x/locale"
 	"github.com/dgf/go-ssr-x/sqlite3"
 	"github.com/google/uuid"
 	"golang.org/x/text/language"
 
-	_ "modernc.org/sqlite"
+	_ "github.com/dgf/go-ssr-x/postgres"
 )
 
 type Globals struct {
-	File string
+	Output  string
+	Storage string
+	DSN     string
+	File    string
+	User    string
+}
+
+// dsn returns the storage URL, the DSN option or the default of the storage option.
+func (g *Globals) dsn() string {
+	switch {
+	case g.DSN != "":
+		return g.DSN
+	case g.Storage == "memory":
+		return "memory://"
+	case g.Storage == "database":
+		return ""
+	default:
+		return sqlite3.Scheme + "://" + g.File
+	}
+}
+
+// openStorage opens the storage of the globals, its errors are localized.
+func openStorage(ctx context.Context, globals *Globals) (entity.Storage, error) {
+	dsn := globals.dsn()
+	if dsn == "" {
+		return nil, errors.New(locale.TranslateData(ctx, "storage_dsn_missing", map[string]string{"storage": globals.Storage}))
+	}
+
+	scheme, _ := entity.Scheme(dsn)
+	if !slices.Contains(entity.Schemes(), scheme) {
+		return nil, errors.New(locale.TranslateData(ctx, "storage_scheme_unknown", map[string]string{
+			"scheme":  scheme,
+			"schemes": strings.Join(entity.Schemes(), ", "),
+		}))
+	}
+
+	storage, err := entity.Open(ctx, dsn)
+	if err != nil {
+		cause := errors.Unwrap(err) // without the scheme prefix
+		if cause == nil {
+			cause = err
+		}
+
+		return nil, errors.New(locale.TranslateData(ctx, "storage_unavailable", map[string]string{
+			"scheme": scheme,
+			"error":  cause.Error(),
+		}))
+	}
+
+	return storage, nil
 }
 
 func runWithStorage(globals *Globals, run func(context.Context, io.Writer, entity.Storage) error) error {
 	ctx := context.Background()
 	ctx = locale.WithLocale(ctx, language.German)
 
-	storage, err := sqlite3.NewFile(ctx, globals.File)
+	storage, err := openStorage(ctx, globals)
 	if err != nil {
 		return err
 	}
 
-	return run(ctx, os.Stdout, storage)
+	ctx, err = userContext(ctx, storage, globals.User)
+	if err == nil {
+		err = run(ctx, os.Stdout, storage)
+	}
+
+	return errors.Join(err, storage.Close())
+}
+
+// userContext scopes the context to the named user, all tasks if the name is empty.
+func userContext(ctx context.Context, storage entity.Storage, name string) (context.Context, error) {
+	if name == "" {
+		return ctx, nil
+	}
+
+	user, found, err := storage.UserByName(ctx, name)
+	if err != nil {
+		return ctx, err
+	}
+
+	if !found {
+		return ctx, errors.New(locale.TranslateData(ctx, "not_found_user", map[string]string{"name": name}))
+	}
+
+	return entity.WithUser(ctx, user), nil
+}
+
+func statusLabel(ctx context.Context, status entity.TaskStatus) string {
+	return locale.Translate(ctx, "task_status_"+strings.ReplaceAll(status.String(), "-", "_"))
+}
+
+func tagList(tags []string) string {
+	list := make([]string, len(tags))
+	for t, tag := range tags {
+		list[t] = "#" + tag
+	}
+
+	return strings.Join(list, " ")
+}
+
+// passRecurrence generates the next occurrence of a finished recurring task and returns the passed task with the ID of
+// its new occurrence.
+func passRecurrence(ctx context.Context, storage entity.Storage, task entity.Task) (entity.Task, uuid.UUID, error) {
+	if !task.Recurs(time.Now()) {
+		return task, uuid.Nil, nil
+	}
+
+	occurrence, recurred, err := storage.RecurTask(ctx, task.ID, time.Now())
+	if err != nil || !recurred {
+		return task, uuid.Nil, err
+	}
+
+	passed, found, err := storage.Task(ctx, task.ID)
+	if err != nil || !found {
+		return task, occurrence.NextID, err
+	}
+
+	return passed, occurrence.NextID, nil
 }
 
 type PageTasksCmd struct {
-	Page   int `default:"1" help:"Page number to show."`
-	Size   int `default:"10" help:"Page size to show."`
-	Sort   entity.TaskSort
-	Order  entity.SortOrder
-	Filter string `help:"Match subject filter."`
+	Page    int `default:"1" help:"Page number to show."`
+	Size    int `default:"10" help:"Page size to show."`
+	Sort    entity.TaskSort
+	Order   entity.SortOrder
+	Search  string   `aliases:"filter" help:"Search subject and description."`
+	Status  []string `help:"Match status (open, in-progress, done, cancelled)."`
+	Tag     []string `help:"Match tasks with all tags."`
+	Blocked bool     `help:"Match tasks with unfinished blockers."`
 }
 
 func (cmd *PageTasksCmd) Run(globals *Globals) error {
 	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
-		q := entity.TaskQuery{
-			Page:   cmd.Page,
-			Size:   cmd.Size,
-			Sort:   cmd.Sort,
-			Order:  cmd.Order,
-			Filter: cmd.Filter,
+		statuses := []entity.TaskStatus{}
+		for _, value := range cmd.Status {
+			status, ok := entity.ParseTaskStatus(value)
+			if !ok {
+				return fmt.Errorf("unknown task status: %s", value)
+			}
+			statuses = append(statuses, status)
 		}
 
-		page, err := storage.Tasks(ctx, q)
+		tags, err := entity.NormalizeTags(cmd.Tag)
 		if err != nil {
 			return err
 		}
 
-		fmt.Fprintf(w, "\n %s (%d / %d)   %s: %d, %s: %s %s, %s: %s \n\n",
-			locale.Translate(ctx, "page_title"),
-			page.Results,
-			page.Count,
-			locale.Translate(ctx, "page_number"),
-			q.Page,
-			locale.Translate(ctx, "task_sort"),
-			q.Order,
-			q.Sort,
-			locale.Translate(ctx, "task_subject"),
-			q.Filter)
-
-		fmt.Fprintf(w, " # \t %s \t %s \t %s \n\n",
-			"ID                                  ",
-			locale.Translate(ctx, "task_due_date"),
-			locale.Translate(ctx, "task_subject"))
+		q := entity.TaskQuery{
+			Page:     cmd.Page,
+			Size:     cmd.Size,
+			Sort:     cmd.Sort,
+			Order:    cmd.Order,
+			Search:   cmd.Search,
+			Statuses: statuses,
+			Tags:     tags,
+			Blocked:  cmd.Blocked,
+		}
 
-		for t, task := range page.Tasks {
-			fmt.Fprintf(w, " %d \t %s \t %s \t %s\n",
-				page.Start+t+1,
-				task.ID,
-				locale.LocalizeDate(ctx, task.DueDate),
-				task.Subject)
+		page, err := storage.Tasks(ctx, q)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		return writePage(ctx, w, globals.Output, q, page)
 	})
 }
 
 type AddTaskCmd struct {
-	Subject string `arg:"" required:""`
+	Subject string    `arg:"" required:""`
+	Tag     []string  `help:"Tag the task."`
+	Parent  uuid.UUID `help:"Add a subtask of the parent task ID."`
+	Repeat  string    `help:"Repeat the task by an RRULE, e.g. FREQ=WEEKLY;BYDAY=MO." placeholder:"RRULE"`
 }
 
 func (cmd *AddTaskCmd) Run(globals *Globals) error {
 	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		recurrence, err := entity.ParseRecurrence(cmd.Repeat)
+		if err != nil {
+			return invalidField(ctx, "recurrence", cmd.Repeat)
+		}
+
 		data := entity.TaskData{
-			DueDate: time.Now().Add(14 * 24 * time.Hour), // 2 weeks
-			Subject: cmd.Subject,
+			DueDate:    time.Now().Add(14 * 24 * time.Hour), // 2 weeks
+			Subject:    cmd.Subject,
+			Tags:       cmd.Tag,
+			ParentID:   cmd.Parent,
+			Recurrence: recurrence,
 		}
 
 		id, err := storage.AddTask(ctx, data)
+		if errors.Is(err, entity.ErrInvalidParent) {
+			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.Parent.String()}))
+		}
 		if err != nil {
 			return err
 		}
+
+		if globals.Output != outputTable {
+			task, _, err := storage.Task(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			return writeTask(ctx, w, globals.Output, task)
+		}
 		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_created", map[string]string{"id": id.String()}))
 
 		return nil
@@ -123,43 +249,230 @@ func (cmd *ShowTaskCmd) Run(globals *Globals) error {
 			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.ID.String()}))
 		}
 
-		fmt.Fprintf(w, "\n %s:\t%s\n",
-			locale.Translate(ctx, "task_subject"), task.Subject)
-		fmt.Fprintf(w, " %s:\t%s\n\n",
-			locale.Translate(ctx, "task_due_date"), locale.LocalizeDate(ctx, task.DueDate))
+		return writeTask(ctx, w, globals.Output, task)
+	})
+}
+
+type DeleteTaskCmd struct {
+	ID      uuid.UUID `arg:"" required:"" help:"ID of task to delete."`
+	Cascade bool      `help:"Delete the subtasks too, they move to the parent otherwise."`
+}
+
+func (cmd *DeleteTaskCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		if cmd.Cascade {
+			_, err := storage.DeleteTaskTree(ctx, cmd.ID)
+
+			return err
+		}
+
+		return storage.DeleteTask(ctx, cmd.ID)
+	})
+}
+
+type DoneTaskCmd struct {
+	ID uuid.UUID `arg:"" required:"" help:"ID of task to complete."`
+}
+
+func (cmd *DoneTaskCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		task, found, err := storage.UpdateTaskStatus(ctx, cmd.ID, entity.TaskStatusDone)
+		var transition entity.TransitionError
+		if errors.As(err, &transition) {
+			return errors.New(locale.TranslateData(ctx, "conflict_task_status", map[string]string{
+				"from": statusLabel(ctx, transition.Task.Status),
+				"to":   statusLabel(ctx, entity.TaskStatusDone),
+			}))
+		}
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.ID.String()}))
+		}
+
+		task, nextID, err := passRecurrence(ctx, storage, task)
+		if err != nil {
+			return err
+		}
+
+		if globals.Output != outputTable {
+			return writeTask(ctx, w, globals.Output, task)
+		}
 
-		r, _ := glamour.NewTermRenderer(glamour.WithAutoStyle())
-		out, err := r.Render(task.Description)
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_status_updated", map[string]string{
+			"id":     cmd.ID.String(),
+			"status": statusLabel(ctx, entity.TaskStatusDone),
+		}))
+		if nextID != uuid.Nil {
+			fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_recurred", map[string]string{"id": nextID.String()}))
+		}
+
+		return nil
+	})
+}
+
+type BlockTaskCmd struct {
+	ID      uuid.UUID `arg:"" required:"" help:"ID of the blocked task."`
+	Blocker uuid.UUID `arg:"" required:"" help:"ID of the task to finish first."`
+}
+
+func (cmd *BlockTaskCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		_, found, err := storage.Task(ctx, cmd.ID)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintln(w, out)
+
+		if !found {
+			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.ID.String()}))
+		}
+
+		data := map[string]string{"id": cmd.ID.String(), "blocker": cmd.Blocker.String()}
+		err = storage.AddDependency(ctx, cmd.ID, cmd.Blocker)
+		if errors.Is(err, entity.ErrDependencyCycle) {
+			return errors.New(locale.TranslateData(ctx, "conflict_task_dependency", data))
+		}
+		if errors.Is(err, entity.ErrNotFound) {
+			return errors.New(locale.TranslateData(ctx, "not_found_task", map[string]string{"id": cmd.Blocker.String()}))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_dependency_added", data))
 
 		return nil
 	})
 }
 
-type DeleteTaskCmd struct {
-	ID uuid.UUID `arg:"" required:"" help:"ID of task to delete."`
+type UnblockTaskCmd struct {
+	ID      uuid.UUID `arg:"" required:"" help:"ID of the blocked task."`
+	Blocker uuid.UUID `arg:"" required:"" help:"ID of the blocker to remove."`
 }
 
-func (cmd *DeleteTaskCmd) Run(globals *Globals) error {
+func (cmd *UnblockTaskCmd) Run(globals *Globals) error {
 	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
-		return storage.DeleteTask(ctx, cmd.ID)
+		data := map[string]string{"id": cmd.ID.String(), "blocker": cmd.Blocker.String()}
+
+		err := storage.RemoveDependency(ctx, cmd.ID, cmd.Blocker)
+		if errors.Is(err, entity.ErrNotFound) {
+			return errors.New(locale.TranslateData(ctx, "not_found_task_dependency", data))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_task_dependency_removed", data))
+
+		return nil
+	})
+}
+
+// PlanTasksCmd lists the unfinished tasks in one page, the blockers before the tasks they block.
+type PlanTasksCmd struct{}
+
+func (cmd *PlanTasksCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		plan, err := storage.WorkPlan(ctx)
+		if err != nil {
+			return err
+		}
+
+		count, err := storage.TaskCount(ctx)
+		if err != nil {
+			return err
+		}
+
+		q := entity.TaskQuery{Page: 1, Size: len(plan)}
+		page := entity.TaskPage{Tasks: plan, Count: count, Results: len(plan)}
+
+		return writePage(ctx, w, globals.Output, q, page)
+	})
+}
+
+type AddUserCmd struct {
+	Name     string `arg:"" required:""`
+	Password string `env:"TASKS_PASSWORD" help:"Password of the user, read from stdin if empty."`
+}
+
+func (cmd *AddUserCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		password := cmd.Password
+		if password == "" {
+			fmt.Fprint(w, locale.Translate(ctx, "user_password_prompt")+": ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+
+		user, err := storage.AddUser(ctx, cmd.Name, password)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_user_created", map[string]string{"name": user.Name}))
+
+		return nil
+	})
+}
+
+type ClaimUserCmd struct {
+	Name string `arg:"" required:""`
+}
+
+func (cmd *ClaimUserCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		user, found, err := storage.UserByName(ctx, cmd.Name)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return errors.New(locale.TranslateData(ctx, "not_found_user", map[string]string{"name": cmd.Name}))
+		}
+
+		claimed, err := storage.ClaimTasks(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_tasks_claimed", map[string]string{
+			"count": strconv.Itoa(claimed),
+			"name":  user.Name,
+		}))
+
+		return nil
 	})
 }
 
 var CLI struct {
-	File string `default:".tasks.sqlite" help:"File based storage backend path (your data)."`
+	Output  string `short:"o" default:"table" enum:"table,json,jsonl,csv,yaml,markdown" help:"Output format: table, json, jsonl, csv, yaml or markdown."`
+	Storage string `default:"file" enum:"file,database,memory" help:"Storage backend without DSN: file, database or memory (empty on every run)."`
+	DSN     string `env:"TASKS_DSN" help:"Storage URL, e.g. postgres://user@localhost/tasks, overrides the storage and the file."`
+	File    string `default:".tasks.sqlite" help:"File based storage backend path (your data)."`
+	User    string `help:"Scope the tasks to the user, all tasks if empty."`
+
+	List    PageTasksCmd   `cmd:"" default:"1" help:"List tasks."`
+	Show    ShowTaskCmd    `cmd:"" help:"Show task."`
+	Add     AddTaskCmd     `cmd:"" help:"Add task."`
+	Edit    EditTaskCmd    `cmd:"" help:"Edit a task in the $EDITOR or set its fields."`
+	Done    DoneTaskCmd    `cmd:"" help:"Complete a task."`
+	Delete  DeleteTaskCmd  `cmd:"" aliases:"del" help:"Delete a task."`
+	Block   BlockTaskCmd   `cmd:"" help:"Block a task by another one."`
+	Unblock UnblockTaskCmd `cmd:"" help:"Remove the blocker of a task."`
+	Plan    PlanTasksCmd   `cmd:"" help:"List the unfinished tasks in work order, blockers first."`
+
+	Export ExportTasksCmd `cmd:"" help:"Export all tasks with all fields."`
+	Import ImportTasksCmd `cmd:"" help:"Import tasks, it updates the tasks of existing IDs."`
 
-	List   PageTasksCmd  `cmd:"" default:"1" help:"List tasks."`
-	Show   ShowTaskCmd   `cmd:"" help:"Show task."`
-	Add    AddTaskCmd    `cmd:"" help:"Add task."`
-	Delete DeleteTaskCmd `cmd:"" aliases:"del" help:"Delete a task."`
+	Users struct {
+		Add   AddUserCmd   `cmd:"" help:"Add user."`
+		Claim ClaimUserCmd `cmd:"" help:"Assign the tasks without user to the user, e.g. after an upgrade."`
+	} `cmd:"" name:"user" help:"Manage users."`
 }
 
 func main() {
 	ctx := kong.Parse(&CLI)
-	err := ctx.Run(&Globals{File: CLI.File})
+	err := ctx.Run(&Globals{Output: CLI.Output, Storage: CLI.Storage, DSN: CLI.DSN, File: CLI.File, User: CLI.User})
 	ctx.FatalIfErrorf(err)
 }
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/locale"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputJSONL    = "jsonl"
+	outputCSV      = "csv"
+	outputYAML     = "yaml"
+	outputMarkdown = "markdown"
+)
+
+// taskRecord is the output structure of a task, e.g. of show, add, edit and done.
+type taskRecord struct {
+	ID          uuid.UUID  `json:"id"                    yaml:"id"`
+	CreatedAt   time.Time  `json:"createdAt"             yaml:"createdAt"`
+	DueDate     string     `json:"dueDate"               yaml:"dueDate"` // YYYY-MM-DD, empty without due date
+	Subject     string     `json:"subject"               yaml:"subject"`
+	Description string     `json:"description"           yaml:"description"`
+	Status      string     `json:"status"                yaml:"status"` // open, in-progress, done or cancelled
+	StartedAt   *time.Time `json:"startedAt,omitempty"   yaml:"startedAt,omitempty"`
+	DoneAt      *time.Time `json:"doneAt,omitempty"      yaml:"doneAt,omitempty"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty" yaml:"cancelledAt,omitempty"`
+	Tags        []string   `json:"tags"                  yaml:"tags"`
+	Version     int64      `json:"version"               yaml:"version"`
+	ParentID    *uuid.UUID `json:"parentId,omitempty"    yaml:"parentId,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"  yaml:"recurrence,omitempty"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO
+	NextID      *uuid.UUID `json:"nextId,omitempty"      yaml:"nextId,omitempty"`     // of a passed on recurrence
+}
+
+// taskOverviewRecord is the output structure of a listed task.
+type taskOverviewRecord struct {
+	ID        uuid.UUID        `json:"id"                  yaml:"id"`
+	CreatedAt time.Time        `json:"createdAt"           yaml:"createdAt"`
+	DueDate   string           `json:"dueDate"             yaml:"dueDate"`
+	Subject   string           `json:"subject"             yaml:"subject"`
+	Status    string           `json:"status"              yaml:"status"`
+	Tags      []string         `json:"tags"                yaml:"tags"`
+	Relevance float64          `json:"relevance,omitempty" yaml:"relevance,omitempty"` // of a search
+	Checklist *checklistRecord `json:"checklist,omitempty" yaml:"checklist,omitempty"` // of a description with task list items
+	Blockers  int              `json:"blockers,omitempty"  yaml:"blockers,omitempty"`  // unfinished tasks blocking this one
+	Blocking  int              `json:"blocking,omitempty"  yaml:"blocking,omitempty"`  // unfinished tasks blocked by this one
+}
+
+type checklistRecord struct {
+	Done  int `json:"done"  yaml:"done"`
+	Total int `json:"total" yaml:"total"`
+}
+
+// pageRecord is the output structure of a task list, the JSON lines and CSV formats contain the tasks only.
+type pageRecord struct {
+	Page    int                  `json:"page"    yaml:"page"`
+	Size    int                  `json:"size"    yaml:"size"`
+	Start   int                  `json:"start"   yaml:"start"`
+	Count   int                  `json:"count"   yaml:"count"`
+	Results int                  `json:"results" yaml:"results"`
+	Tasks   []taskOverviewRecord `json:"tasks"   yaml:"tasks"`
+}
+
+var (
+	taskColumns         = []string{"id", "createdAt", "dueDate", "subject", "description", "status", "startedAt", "doneAt", "cancelledAt", "tags", "version", "parentId", "recurrence", "nextId"}
+	taskOverviewColumns = []string{"id", "createdAt", "dueDate", "subject", "status", "tags", "relevance", "checklist", "blockers", "blocking"}
+)
+
+func newTaskRecord(task entity.Task) taskRecord {
+	var parentID *uuid.UUID
+	if task.ParentID != uuid.Nil {
+		parentID = &task.ParentID
+	}
+
+	var nextID *uuid.UUID
+	if task.NextID != uuid.Nil {
+		nextID = &task.NextID
+	}
+
+	return taskRecord{
+		ID:          task.ID,
+		CreatedAt:   task.CreatedAt,
+		DueDate:     outputDate(task.DueDate),
+		Subject:     task.Subject,
+		Description: task.Description,
+		Status:      task.Status.String(),
+		StartedAt:   task.StartedAt,
+		DoneAt:      task.DoneAt,
+		CancelledAt: task.CancelledAt,
+		Tags:        nonNil(task.Tags),
+		Version:     task.Version,
+		ParentID:    parentID,
+		Recurrence:  task.Recurrence.String(),
+		NextID:      nextID,
+	}
+}
+
+func newPageRecord(query entity.TaskQuery, page entity.TaskPage) pageRecord {
+	tasks := make([]taskOverviewRecord, len(page.Tasks))
+	for t, task := range page.Tasks {
+		tasks[t] = taskOverviewRecord{
+			ID:        task.ID,
+			CreatedAt: task.CreatedAt,
+			DueDate:   outputDate(task.DueDate),
+			Subject:   task.Subject,
+			Status:    task.Status.String(),
+			Tags:      nonNil(task.Tags),
+			Relevance: task.Relevance,
+			Blockers:  task.Blockers,
+			Blocking:  task.Blocking,
+		}
+		if task.Checklist.Total > 0 {
+			tasks[t].Checklist = &checklistRecord{Done: task.Checklist.Done, Total: task.Checklist.Total}
+		}
+	}
+
+	return pageRecord{
+		Page:    query.Page,
+		Size:    query.Size,
+		Start:   page.Start,
+		Count:   page.Count,
+		Results: page.Results,
+		Tasks:   tasks,
+	}
+}
+
+func outputDate(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+
+	return d.Format(time.DateOnly)
+}
+
+func outputID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+func outputTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func outputRelevance(relevance float64) string {
+	if relevance == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(relevance, 'f', -1, 64)
+}
+
+func outputChecklist(checklist *checklistRecord) string {
+	if checklist == nil {
+		return ""
+	}
+
+	return strconv.Itoa(checklist.Done) + "/" + strconv.Itoa(checklist.Total)
+}
+
+// outputCount returns an empty value of zero.
+func outputCount(count int) string {
+	if count == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(count)
+}
+
+func nonNil(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// writePage writes the task list in the output format.
+func writePage(ctx context.Context, w io.Writer, format string, query entity.TaskQuery, page entity.TaskPage) error {
+	record := newPageRecord(query, page)
+
+	switch format {
+	case outputJSON:
+		return writeJSON(w, record)
+	case outputJSONL:
+		return writeJSONL(w, record.Tasks)
+	case outputYAML:
+		return writeYAML(w, record)
+	case outputCSV:
+		rows := make([][]string, len(record.Tasks))
+		for t, task := range record.Tasks {
+			rows[t] = []string{
+				task.ID.String(),
+				task.CreatedAt.Format(time.RFC3339),
+				task.DueDate,
+				task.Subject,
+				task.Status,
+				strings.Join(task.Tags, ","),
+				outputRelevance(task.Relevance),
+				outputChecklist(task.Checklist),
+				outputCount(task.Blockers),
+				outputCount(task.Blocking),
+			}
+		}
+
+		return writeCSV(w, taskOverviewColumns, rows)
+	case outputMarkdown:
+		return writePageMarkdown(ctx, w, query, page)
+	default:
+		return writePageTable(ctx, w, query, page)
+	}
+}
+
+// writeTask writes the task in the output format.
+func writeTask(ctx context.Context, w io.Writer, format string, task entity.Task) error {
+	record := newTaskRecord(task)
+
+	switch format {
+	case outputJSON:
+		return writeJSON(w, record)
+	case outputJSONL:
+		return writeJSONL(w, []taskRecord{record})
+	case outputYAML:
+		return writeYAML(w, record)
+	case outputCSV:
+		return writeCSV(w, taskColumns, [][]string{{
+			record.ID.String(),
+			record.CreatedAt.Format(time.RFC3339),
+			record.DueDate,
+			record.Subject,
+			record.Description,
+			record.Status,
+			outputTime(record.StartedAt),
+			outputTime(record.DoneAt),
+			outputTime(record.CancelledAt),
+			strings.Join(record.Tags, ","),
+			strconv.FormatInt(record.Version, 10),
+			outputID(record.ParentID),
+			record.Recurrence,
+			outputID(record.NextID),
+		}})
+	case outputMarkdown:
+		return writeTaskMarkdown(ctx, w, task)
+	default:
+		return writeTaskTable(ctx, w, task)
+	}
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+func writeJSONL[T any](w io.Writer, values []T) error {
+	encoder := json.NewEncoder(w)
+	for _, value := range values {
+		err := encoder.Encode(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeYAML(w io.Writer, value any) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err := encoder.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	return writer.WriteAll(rows)
+}
+
+func writePageTable(ctx context.Context, w io.Writer, query entity.TaskQuery, page entity.TaskPage) error {
+	fmt.Fprintf(w, "\n %s (%d / %d)   %s: %d, %s: %s %s, %s: %s \n\n",
+		locale.Translate(ctx, "page_title"),
+		page.Results,
+		page.Count,
+		locale.Translate(ctx, "page_number"),
+		query.Page,
+		locale.Translate(ctx, "task_sort"),
+		query.Order,
+		query.Sort,
+		locale.Translate(ctx, "task_search"),
+		query.Search)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, " #\tID\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		locale.Translate(ctx, "task_due_date"),
+		locale.Translate(ctx, "task_status"),
+		locale.Translate(ctx, "task_subject"),
+		locale.Translate(ctx, "task_checklist"),
+		locale.Translate(ctx, "task_blocked_by"),
+		locale.Translate(ctx, "task_blocking"),
+		locale.Translate(ctx, "task_tags"))
+
+	for t, task := range page.Tasks {
+		fmt.Fprintf(table, " %d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			page.Start+t+1,
+			task.ID,
+			locale.LocalizeDate(ctx, task.DueDate),
+			statusLabel(ctx, task.Status),
+			task.Subject,
+			checklistProgress(task.Checklist),
+			outputCount(task.Blockers),
+			outputCount(task.Blocking),
+			tagList(task.Tags))
+	}
+
+	return table.Flush()
+}
+
+func writeTaskTable(ctx context.Context, w io.Writer, task entity.Task) error {
+	fields := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(fields)
+	fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_subject"), task.Subject)
+	fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_due_date"), locale.LocalizeDate(ctx, task.DueDate))
+	if !task.Recurrence.IsZero() {
+		fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_recurrence"), task.Recurrence)
+	}
+	if task.NextID != uuid.Nil {
+		fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_next_occurrence"), task.NextID)
+	}
+	fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_status"), statusLabel(ctx, task.Status))
+	fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_tags"), strings.Join(task.Tags, ", "))
+	if task.ParentID != uuid.Nil {
+		fmt.Fprintf(fields, " %s:\t%s\n", locale.Translate(ctx, "task_parent"), task.ParentID)
+	}
+	fmt.Fprintln(fields)
+	err := fields.Flush()
+	if err != nil {
+		return err
+	}
+
+	r, _ := glamour.NewTermRenderer(glamour.WithAutoStyle())
+	out, err := r.Render(task.Description)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, out)
+
+	return nil
+}
+
+func writePageMarkdown(ctx context.Context, w io.Writer, query entity.TaskQuery, page entity.TaskPage) error {
+	fmt.Fprintf(w, "## %s (%d / %d)\n\n", locale.Translate(ctx, "page_title"), page.Results, page.Count)
+	fmt.Fprintf(w, "%s: %d, %s: %s %s\n\n",
+		locale.Translate(ctx, "page_number"), query.Page,
+		locale.Translate(ctx, "task_sort"), query.Order, query.Sort)
+
+	fmt.Fprintf(w, "| # | ID | %s | %s | %s | %s | %s | %s | %s |\n",
+		locale.Translate(ctx, "task_due_date"),
+		locale.Translate(ctx, "task_status"),
+		locale.Translate(ctx, "task_subject"),
+		locale.Translate(ctx, "task_checklist"),
+		locale.Translate(ctx, "task_blocked_by"),
+		locale.Translate(ctx, "task_blocking"),
+		locale.Translate(ctx, "task_tags"))
+	fmt.Fprintln(w, "|--:|----|----|----|----|--:|--:|--:|----|")
+
+	for t, task := range page.Tasks {
+		_, err := fmt.Fprintf(w, "| %d | `%s` | %s | %s | %s | %s | %s | %s | %s |\n",
+			page.Start+t+1,
+			task.ID,
+			locale.LocalizeDate(ctx, task.DueDate),
+			statusLabel(ctx, task.Status),
+			markdownCell(task.Subject),
+			checklistProgress(task.Checklist),
+			outputCount(task.Blockers),
+			outputCount(task.Blocking),
+			markdownCell(tagList(task.Tags)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTaskMarkdown(ctx context.Context, w io.Writer, task entity.Task) error {
+	fmt.Fprintf(w, "# %s\n\n- ID: `%s`\n- %s: %s\n- %s: %s\n- %s: %s\n",
+		task.Subject,
+		task.ID,
+		locale.Translate(ctx, "task_due_date"), locale.LocalizeDate(ctx, task.DueDate),
+		locale.Translate(ctx, "task_status"), statusLabel(ctx, task.Status),
+		locale.Translate(ctx, "task_tags"), strings.Join(task.Tags, ", "))
+	if task.ParentID != uuid.Nil {
+		fmt.Fprintf(w, "- %s: `%s`\n", locale.Translate(ctx, "task_parent"), task.ParentID)
+	}
+	if !task.Recurrence.IsZero() {
+		fmt.Fprintf(w, "- %s: `%s`\n", locale.Translate(ctx, "task_recurrence"), task.Recurrence)
+	}
+	if task.NextID != uuid.Nil {
+		fmt.Fprintf(w, "- %s: `%s`\n", locale.Translate(ctx, "task_next_occurrence"), task.NextID)
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", task.Description)
+
+	return err
+}
+
+// checklistProgress returns the progress, e.g. 3/7, empty without task list items.
+func checklistProgress(checklist entity.Checklist) string {
+	if checklist.Total == 0 {
+		return ""
+	}
+
+	return checklist.String()
+}
+
+// markdownCell escapes the pipes of a table cell.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
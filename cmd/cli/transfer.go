@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/locale"
+	"github.com/dgf/go-ssr-x/transfer"
+)
+
+type ExportTasksCmd struct {
+	Format string `short:"f" default:"jsonl" enum:"jsonl,csv,markdown" help:"Export format: jsonl, csv or markdown (a file per task)."`
+	Path   string `arg:"" optional:"" help:"Output file, the directory of markdown, a tar archive to stdout if empty."`
+}
+
+func (cmd *ExportTasksCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		if cmd.Path == "" || cmd.Path == "-" {
+			encoder, err := transfer.NewEncoder(cmd.Format, w)
+			if err != nil {
+				return err
+			}
+			_, err = transfer.Export(ctx, storage, encoder)
+
+			return err
+		}
+
+		count, err := cmd.export(ctx, storage)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, locale.TranslateData(ctx, "ok_tasks_exported", map[string]string{
+			"count": strconv.Itoa(count),
+			"path":  cmd.Path,
+		}))
+
+		return nil
+	})
+}
+
+func (cmd *ExportTasksCmd) export(ctx context.Context, storage entity.Storage) (int, error) {
+	if cmd.Format == transfer.FormatMarkdown {
+		encoder, err := transfer.NewMarkdownDirEncoder(cmd.Path)
+		if err != nil {
+			return 0, err
+		}
+
+		return transfer.Export(ctx, storage, encoder)
+	}
+
+	file, err := os.Create(cmd.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	encoder, err := transfer.NewEncoder(cmd.Format, file)
+	if err != nil {
+		return 0, errors.Join(err, file.Close())
+	}
+	count, err := transfer.Export(ctx, storage, encoder)
+
+	return count, errors.Join(err, file.Close())
+}
+
+type ImportTasksCmd struct {
+	Format string `short:"f" default:"jsonl" enum:"jsonl,csv,markdown" help:"Import format: jsonl, csv or markdown (a directory or a tar archive)."`
+	DryRun bool   `help:"Validate and count the changes without saving them."`
+	Path   string `arg:"" optional:"" help:"Input file or directory of markdown, stdin if empty."`
+}
+
+func (cmd *ImportTasksCmd) Run(globals *Globals) error {
+	return runWithStorage(globals, func(ctx context.Context, w io.Writer, storage entity.Storage) error {
+		decoder, closeInput, err := cmd.decoder()
+		if err != nil {
+			return err
+		}
+
+		report, err := transfer.Import(ctx, storage, decoder, cmd.DryRun)
+		err = errors.Join(err, closeInput())
+		if err != nil {
+			return err
+		}
+
+		err = writeReport(ctx, w, globals.Output, report)
+		if err != nil {
+			return err
+		}
+
+		if report.Failed > 0 {
+			return errors.New(locale.TranslateData(ctx, "failed_import_rows", map[string]string{
+				"failed": strconv.Itoa(report.Failed),
+				"rows":   strconv.Itoa(report.Rows),
+			}))
+		}
+
+		return nil
+	})
+}
+
+// decoder returns the decoder of the path and a function to close its input.
+func (cmd *ImportTasksCmd) decoder() (transfer.Decoder, func() error, error) {
+	noClose := func() error { return nil }
+	if cmd.Path == "" || cmd.Path == "-" {
+		decoder, err := transfer.NewDecoder(cmd.Format, os.Stdin)
+
+		return decoder, noClose, err
+	}
+
+	if cmd.Format == transfer.FormatMarkdown {
+		info, err := os.Stat(cmd.Path)
+		if err != nil {
+			return nil, noClose, err
+		}
+
+		if info.IsDir() {
+			decoder, err := transfer.NewMarkdownDirDecoder(cmd.Path)
+
+			return decoder, noClose, err
+		}
+	}
+
+	file, err := os.Open(cmd.Path)
+	if err != nil {
+		return nil, noClose, err
+	}
+
+	decoder, err := transfer.NewDecoder(cmd.Format, file)
+	if err != nil {
+		return nil, noClose, errors.Join(err, file.Close())
+	}
+
+	return decoder, file.Close, nil
+}
+
+// writeReport writes the import report in the output format, the JSON lines and CSV formats contain the row errors only.
+func writeReport(ctx context.Context, w io.Writer, format string, report transfer.Report) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, report)
+	case outputJSONL:
+		return writeJSONL(w, report.Errors)
+	case outputYAML:
+		return writeYAML(w, report)
+	case outputCSV:
+		rows := make([][]string, len(report.Errors))
+		for e, rowErr := range report.Errors {
+			rows[e] = []string{strconv.Itoa(rowErr.Row), rowErr.Name, rowErr.ID, rowErr.Message}
+		}
+
+		return writeCSV(w, []string{"row", "name", "id", "error"}, rows)
+	}
+
+	messageID := "ok_tasks_imported"
+	if report.DryRun {
+		messageID = "ok_tasks_import_checked"
+	}
+	fmt.Fprintln(w, locale.TranslateData(ctx, messageID, map[string]string{
+		"rows":    strconv.Itoa(report.Rows),
+		"created": strconv.Itoa(report.Created),
+		"updated": strconv.Itoa(report.Updated),
+		"failed":  strconv.Itoa(report.Failed),
+	}))
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(table, " %s %d\t%s\t%s\n", locale.Translate(ctx, "import_row"), rowErr.Row, cmp.Or(rowErr.Name, rowErr.ID), rowErr.Message)
+	}
+
+	return table.Flush()
+}
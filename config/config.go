@@ -0,0 +1,228 @@
+// Package config loads the server configuration of a TOML file, the TASKS_* environment variables and the flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/dgf/go-ssr-x/web"
+)
+
+const (
+	StorageMemory   = "memory"
+	StorageFile     = "file"
+	StorageDatabase = "database"
+
+	DefaultFile = ".tasks.sqlite" // of the file storage without DSN
+	fileScheme  = "sqlite://"
+)
+
+// defaultDSNs of the storages without DSN.
+var defaultDSNs = map[string]string{
+	StorageMemory: "memory://",
+	StorageFile:   fileScheme + DefaultFile,
+}
+
+var storages = []string{StorageMemory, StorageFile, StorageDatabase}
+
+type Config struct {
+	Addr         string        `toml:"addr"`
+	Storage      string        `toml:"storage"`     // memory, file or database, selects the DSN if it is empty
+	DSN          string        `toml:"dsn"`         // storage URL, its scheme selects the backend, see entity.Open
+	SessionKey   string        `toml:"session_key"` // random if empty
+	AdminToken   string        `toml:"admin_token"` // disables the admin endpoints if empty
+	Demo         bool          `toml:"demo"`        // seeds an empty storage with the demo user, always of the memory storage
+	DrainTimeout time.Duration `toml:"drain_timeout"`
+	HTTP         HTTP          `toml:"http"`
+	Log          log.Config    `toml:"log"`
+}
+
+type HTTP struct {
+	ReadTimeout  time.Duration `toml:"read_timeout"`
+	WriteTimeout time.Duration `toml:"write_timeout"`
+	IdleTimeout  time.Duration `toml:"idle_timeout"`
+}
+
+// Default serves the in-memory storage on port 3000.
+func Default() Config {
+	return Config{
+		Addr:         "0.0.0.0:3000",
+		Storage:      StorageMemory,
+		DrainTimeout: web.DefaultDrainTimeout,
+		HTTP: HTTP{
+			ReadTimeout:  web.DefaultReadTimeout,
+			WriteTimeout: web.DefaultWriteTimeout,
+			IdleTimeout:  web.DefaultIdleTimeout,
+		},
+		Log: log.DefaultConfig(),
+	}
+}
+
+// Load returns the defaults overridden by the TOML file, then by the environment variables and then by the flags.
+func Load(name string, args []string) (Config, error) {
+	config := Default()
+	fs := flags{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError), env: map[string]string{}}
+	path := fs.String("config", os.Getenv("TASKS_CONFIG"), "TOML configuration file (TASKS_CONFIG)")
+	fs.register(&config)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return config, err
+	}
+
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	config = Default() // the flags apply last
+	if *path != "" {
+		err := config.decodeFile(*path)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(fs.env)) {
+		value, ok := os.LookupEnv(fs.env[name])
+		if !ok {
+			continue
+		}
+
+		err := fs.Set(name, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid environment variable %s: %w", fs.env[name], err))
+		}
+	}
+	if len(errs) > 0 {
+		return config, errors.Join(errs...)
+	}
+
+	for name, value := range set {
+		_ = fs.Set(name, value) // parsed before
+	}
+
+	_, scheme := entity.Scheme(config.DSN)
+	switch {
+	case config.DSN == "":
+		config.DSN = defaultDSNs[config.Storage]
+	case !scheme && config.Storage == StorageFile:
+		config.DSN = fileScheme + config.DSN // a file path
+	}
+
+	return config, config.Validate()
+}
+
+// Validate returns all invalid values.
+func (c Config) Validate() error {
+	var errs []error
+
+	_, _, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid address %q: %w", c.Addr, err))
+	}
+
+	if !slices.Contains(storages, c.Storage) {
+		errs = append(errs, fmt.Errorf("unknown storage %q, use %s", c.Storage, strings.Join(storages, ", ")))
+	}
+
+	scheme, _ := entity.Scheme(c.DSN)
+	switch {
+	case c.DSN == "" && c.Storage == StorageDatabase:
+		errs = append(errs, errors.New("missing DSN of the database storage"))
+	case !slices.Contains(entity.Schemes(), scheme):
+		errs = append(errs, fmt.Errorf("invalid DSN scheme %q, use %s", scheme, strings.Join(entity.Schemes(), ", ")))
+	}
+
+	for _, timeout := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"drain", c.DrainTimeout},
+		{"HTTP read", c.HTTP.ReadTimeout},
+		{"HTTP write", c.HTTP.WriteTimeout},
+		{"HTTP idle", c.HTTP.IdleTimeout},
+	} {
+		if timeout.duration <= 0 {
+			errs = append(errs, fmt.Errorf("invalid %s timeout %s, use a positive duration", timeout.name, timeout.duration))
+		}
+	}
+
+	return errors.Join(append(errs, c.Log.Validate())...)
+}
+
+func (c *Config) decodeFile(path string) error {
+	meta, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return fmt.Errorf("invalid configuration file: %w", err)
+	}
+
+	undecoded := meta.Undecoded()
+	if len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+
+		return fmt.Errorf("unknown keys of the configuration file %s: %s", path, strings.Join(keys, ", "))
+	}
+
+	return nil
+}
+
+// flags maps the flag names to their environment variables.
+type flags struct {
+	*flag.FlagSet
+
+	env map[string]string
+}
+
+func (f flags) register(c *Config) {
+	f.string(&c.Addr, "address", "TASKS_ADDR", "web server address")
+	f.string(&c.Storage, "storage", "TASKS_STORAGE", "memory, file or database")
+	f.string(&c.DSN, "dsn", "TASKS_DSN", "storage URL, e.g. "+fileScheme+DefaultFile+" or postgres://user@localhost/tasks")
+	f.string(&c.SessionKey, "session-key", "TASKS_SESSION_KEY", "secret to sign the session cookies, random if empty")
+	f.string(&c.AdminToken, "admin-token", "TASKS_ADMIN_TOKEN", "bearer token of the admin endpoints, disabled if empty")
+	f.bool(&c.Demo, "demo", "TASKS_DEMO", "seed an empty storage with tasks of the demo user, always of the memory storage")
+	f.duration(&c.DrainTimeout, "drain-timeout", "TASKS_DRAIN_TIMEOUT", "wait for active requests on shutdown")
+	f.duration(&c.HTTP.ReadTimeout, "http-read-timeout", "TASKS_HTTP_READ_TIMEOUT", "maximum duration to read a request")
+	f.duration(&c.HTTP.WriteTimeout, "http-write-timeout", "TASKS_HTTP_WRITE_TIMEOUT", "maximum duration to write a response")
+	f.duration(&c.HTTP.IdleTimeout, "http-idle-timeout", "TASKS_HTTP_IDLE_TIMEOUT", "maximum wait for the next request of a connection")
+	f.string(&c.Log.Level, "log-level", "TASKS_LOG_LEVEL", "log level: debug, info, warn or error")
+	f.string(&c.Log.Format, "log-format", "TASKS_LOG_FORMAT", "log format: json or text")
+	f.string(&c.Log.Output, "log-output", "TASKS_LOG_OUTPUT", "log output: stdout, stderr or a file path")
+	f.int(&c.Log.MaxSize, "log-max-size", "TASKS_LOG_MAX_SIZE", "megabytes of the log file before it rotates")
+	f.int(&c.Log.MaxBackups, "log-max-backups", "TASKS_LOG_MAX_BACKUPS", "number of kept rotated log files")
+	f.bool(&c.Log.Source, "log-source", "TASKS_LOG_SOURCE", "log the source code location")
+}
+
+func (f flags) string(p *string, name, env, usage string) {
+	f.StringVar(p, name, *p, usage+" ("+env+")")
+	f.env[name] = env
+}
+
+func (f flags) duration(p *time.Duration, name, env, usage string) {
+	f.DurationVar(p, name, *p, usage+" ("+env+")")
+	f.env[name] = env
+}
+
+func (f flags) int(p *int, name, env, usage string) {
+	f.IntVar(p, name, *p, usage+" ("+env+")")
+	f.env[name] = env
+}
+
+func (f flags) bool(p *bool, name, env, usage string) {
+	f.BoolVar(p, name, *p, usage+" ("+env+")")
+	f.env[name] = env
+}
@@ -0,0 +1,149 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgf/go-ssr-x/config"
+	"github.com/dgf/go-ssr-x/web"
+
+	_ "github.com/dgf/go-ssr-x/postgres"
+	_ "github.com/dgf/go-ssr-x/sqlite3"
+)
+
+const configFile = `
+addr = "127.0.0.1:4000"
+dsn = "sqlite:///var/lib/tasks.sqlite"
+drain_timeout = "20s"
+
+[http]
+read_timeout = "5s"
+write_timeout = "7s"
+
+[log]
+level = "warn"
+`
+
+func TestLoad(t *testing.T) {
+	path := writeFile(t, configFile)
+	t.Setenv("TASKS_CONFIG", path)
+	t.Setenv("TASKS_ADDR", "127.0.0.1:5000")
+	t.Setenv("TASKS_HTTP_READ_TIMEOUT", "6s")
+	t.Setenv("TASKS_DEMO", "true")
+
+	c, err := config.Load("test", []string{"-address", "127.0.0.1:6000", "-http-write-timeout", "8s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		name      string
+		got, want any
+	}{
+		{"flag over environment", c.Addr, "127.0.0.1:6000"},
+		{"flag over file", c.HTTP.WriteTimeout, 8 * time.Second},
+		{"environment over file", c.HTTP.ReadTimeout, 6 * time.Second},
+		{"environment", c.Demo, true},
+		{"file", c.DSN, "sqlite:///var/lib/tasks.sqlite"},
+		{"file timeout", c.DrainTimeout, 20 * time.Second},
+		{"file section", c.Log.Level, "warn"},
+		{"default", c.HTTP.IdleTimeout, web.DefaultIdleTimeout},
+		{"default section", c.Log.Format, "json"},
+	} {
+		if v.got != v.want {
+			t.Errorf("%s %v, want %v", v.name, v.got, v.want)
+		}
+	}
+}
+
+func TestLoadStorage(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+		dsn  string
+	}{
+		{"default", nil, "memory://"},
+		{"file", []string{"-storage", "file"}, "sqlite://.tasks.sqlite"},
+		{"file path", []string{"-storage", "file", "-dsn", "data/tasks.sqlite"}, "sqlite://data/tasks.sqlite"},
+		{"database", []string{"-storage", "database", "-dsn", "postgres://localhost/tasks"}, "postgres://localhost/tasks"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("TASKS_DSN", "")
+
+			loaded, err := config.Load("test", c.args)
+			if err != nil || loaded.DSN != c.dsn {
+				t.Errorf("DSN %q with error %v, want %q", loaded.DSN, err, c.dsn)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{name: "address", args: []string{"-address", "localhost"}},
+		{name: "storage", args: []string{"-storage", "cloud"}},
+		{name: "database without DSN", args: []string{"-storage", "database"}},
+		{name: "DSN scheme", args: []string{"-dsn", "mysql://localhost/tasks"}},
+		{name: "zero timeout", args: []string{"-drain-timeout", "0s"}},
+		{name: "negative timeout", args: []string{"-http-idle-timeout", "-1s"}},
+		{name: "timeout flag", args: []string{"-http-read-timeout", "soon"}},
+		{name: "timeout environment", env: map[string]string{"TASKS_HTTP_WRITE_TIMEOUT": "13"}},
+		{name: "log level", env: map[string]string{"TASKS_LOG_LEVEL": "loud"}},
+		{name: "file key", file: `address = "127.0.0.1:3000"`},
+		{name: "file timeout", file: `drain_timeout = "long"`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("TASKS_DSN", "")
+			for key, value := range c.env {
+				t.Setenv(key, value)
+			}
+
+			args := c.args
+			if c.file != "" {
+				args = append([]string{"-config", writeFile(t, c.file)}, args...)
+			}
+
+			_, err := config.Load("test", args)
+			if err == nil {
+				t.Error("loaded, want an error")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := config.Default()
+	c.DSN = "memory://"
+
+	err := c.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Storage = "cloud"
+	c.HTTP.ReadTimeout = 0
+
+	err = c.Validate()
+	if err == nil {
+		t.Error("validated an unknown storage and a zero timeout, want an error")
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
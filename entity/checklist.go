@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"strconv"
+
+	"github.com/dgf/go-ssr-x/markdown"
+)
+
+// Checklist is the progress of the task list items of a description, e.g. "- [x] done".
+type Checklist struct {
+	Done  int
+	Total int
+}
+
+func ParseChecklist(description string) Checklist {
+	done, total := markdown.Checklist(description)
+
+	return Checklist{Done: done, Total: total}
+}
+
+// String returns the progress, e.g. 3/7.
+func (c Checklist) String() string {
+	return strconv.Itoa(c.Done) + "/" + strconv.Itoa(c.Total)
+}
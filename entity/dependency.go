@@ -0,0 +1,106 @@
+package entity
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrDependencyCycle is wrapped by storage errors of a dependency that would block a task by itself.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// TaskDependencies are the blocked-by relations of a task, both lists ordered by creation.
+type TaskDependencies struct {
+	BlockedBy []TaskOverview // the task can't start until these are done
+	Blocking  []TaskOverview // these wait for the task
+}
+
+// WithDependencies returns the overview with the number of the unfinished blockers and blocked tasks.
+func (o TaskOverview) WithDependencies(dependencies TaskDependencies) TaskOverview {
+	o.Blockers = unfinished(dependencies.BlockedBy)
+	o.Blocking = 0
+	if !o.Status.Finished() {
+		o.Blocking = unfinished(dependencies.Blocking)
+	}
+
+	return o
+}
+
+// SortWorkPlan orders the tasks topologically, the blockers before the tasks they block. The ready tasks follow by
+// due date, tasks without due date last, and creation. The blockers map the task IDs to the IDs of their blockers,
+// blockers that aren't planned are ignored and the tasks of a cycle are appended.
+func SortWorkPlan(tasks []TaskOverview, blockers map[uuid.UUID][]uuid.UUID) []TaskOverview {
+	planned := make(map[uuid.UUID]TaskOverview, len(tasks))
+	for _, t := range tasks {
+		planned[t.ID] = t
+	}
+
+	waiting := map[uuid.UUID]int{}
+	blocking := map[uuid.UUID][]uuid.UUID{}
+	for _, t := range tasks {
+		for _, blocker := range blockers[t.ID] {
+			if _, ok := planned[blocker]; ok {
+				waiting[t.ID]++
+				blocking[blocker] = append(blocking[blocker], t.ID)
+			}
+		}
+	}
+
+	ready := []TaskOverview{}
+	enqueue := func(t TaskOverview) {
+		i, _ := slices.BinarySearchFunc(ready, t, comparePlan)
+		ready = slices.Insert(ready, i, t)
+	}
+	for _, t := range tasks {
+		if waiting[t.ID] == 0 {
+			enqueue(t)
+		}
+	}
+
+	plan := make([]TaskOverview, 0, len(tasks))
+	for len(ready) > 0 {
+		next := ready[0]
+		ready = ready[1:]
+		plan = append(plan, next)
+		for _, id := range blocking[next.ID] {
+			waiting[id]--
+			if waiting[id] == 0 {
+				enqueue(planned[id])
+			}
+		}
+	}
+
+	for _, t := range tasks {
+		if waiting[t.ID] > 0 {
+			plan = append(plan, t)
+		}
+	}
+
+	return plan
+}
+
+func comparePlan(a, b TaskOverview) int {
+	if a.DueDate.IsZero() != b.DueDate.IsZero() {
+		if a.DueDate.IsZero() {
+			return 1
+		}
+
+		return -1
+	}
+
+	return cmp.Or(a.DueDate.Compare(b.DueDate), a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID.String(), b.ID.String()))
+}
+
+func unfinished(tasks []TaskOverview) int {
+	count := 0
+	for _, t := range tasks {
+		if !t.Status.Finished() {
+			count++
+		}
+	}
+
+	return count
+}
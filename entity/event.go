@@ -0,0 +1,279 @@
+package entity
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TaskEventType int64
+
+const (
+	TaskCreated TaskEventType = iota
+	TaskUpdated
+	TaskDeleted
+	TasksChanged // of many tasks of the owner without ID, e.g. a renamed tag
+)
+
+// hubBufferSize is the number of events a subscriber can lag behind before events are dropped.
+const hubBufferSize = 64
+
+var taskEventTypeKeys = []string{
+	"created",
+	"updated",
+	"deleted",
+	"changed",
+}
+
+type TaskEvent struct {
+	Type    TaskEventType
+	ID      uuid.UUID
+	OwnerID uuid.UUID // uuid.Nil if unowned
+}
+
+// TaskEventSource is implemented by storages that notify the changes of all storage clients, e.g. other servers.
+type TaskEventSource interface {
+	// ListenTaskEvents publishes the task changes to the hub until the context is done or the listening fails.
+	ListenTaskEvents(ctx context.Context, hub *Hub) error
+}
+
+// Hub distributes the published task events to the subscribers of the task owners.
+type Hub struct {
+	sync.Mutex
+
+	subscribers []subscriber
+	closed      bool
+}
+
+// subscriber receives the events of the tasks owned by the user.
+type subscriber struct {
+	events chan TaskEvent
+	userID uuid.UUID
+}
+
+// PublishingStorage publishes the task changes of the storage to a hub.
+type PublishingStorage struct {
+	Storage
+
+	hub *Hub
+}
+
+func (t TaskEventType) String() string {
+	return taskEventTypeKeys[t]
+}
+
+func ParseTaskEventType(eventType string) (TaskEventType, bool) {
+	t := slices.Index(taskEventTypeKeys, eventType)
+	if t == -1 {
+		return TaskCreated, false
+	}
+
+	return TaskEventType(t), true
+}
+
+func NewHub() *Hub {
+	return &Hub{}
+}
+
+// Publish sends the event to the subscribers that see the task, a subscriber with a full buffer misses the event.
+func (h *Hub) Publish(event TaskEvent) {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, s := range h.subscribers {
+		if event.OwnerID != s.userID {
+			continue
+		}
+
+		select {
+		case s.events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of the events published for the user and a function to cancel the subscription.
+func (h *Hub) Subscribe(userID uuid.UUID) (<-chan TaskEvent, func()) {
+	h.Lock()
+	defer h.Unlock()
+
+	events := make(chan TaskEvent, hubBufferSize)
+	if h.closed {
+		close(events)
+
+		return events, func() {}
+	}
+	h.subscribers = append(h.subscribers, subscriber{events: events, userID: userID})
+
+	return events, func() {
+		h.Lock()
+		defer h.Unlock()
+
+		i := slices.IndexFunc(h.subscribers, func(s subscriber) bool { return s.events == events })
+		if i != -1 {
+			h.subscribers = slices.Delete(h.subscribers, i, i+1)
+			close(events)
+		}
+	}
+}
+
+// Close ends all subscriptions, e.g. to finish the event streams on shutdown.
+func (h *Hub) Close() {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, s := range h.subscribers {
+		close(s.events)
+	}
+	h.subscribers = nil
+	h.closed = true
+}
+
+func NewPublishingStorage(storage Storage, hub *Hub) *PublishingStorage {
+	return &PublishingStorage{Storage: storage, hub: hub}
+}
+
+func (p *PublishingStorage) AddTask(ctx context.Context, data TaskData) (uuid.UUID, error) {
+	id, err := p.Storage.AddTask(ctx, data)
+	if err == nil {
+		p.publish(ctx, TaskCreated, id)
+	}
+
+	return id, err
+}
+
+func (p *PublishingStorage) DeleteTask(ctx context.Context, id uuid.UUID) error {
+	err := p.Storage.DeleteTask(ctx, id)
+	if err == nil {
+		p.publish(ctx, TaskDeleted, id)
+	}
+
+	return err
+}
+
+func (p *PublishingStorage) UpdateTask(ctx context.Context, id uuid.UUID, data TaskData) (Task, bool, error) {
+	task, found, err := p.Storage.UpdateTask(ctx, id, data)
+	if err == nil && found {
+		p.publish(ctx, TaskUpdated, id)
+	}
+
+	return task, found, err
+}
+
+func (p *PublishingStorage) UpdateTaskStatus(ctx context.Context, id uuid.UUID, status TaskStatus) (Task, bool, error) {
+	task, found, err := p.Storage.UpdateTaskStatus(ctx, id, status)
+	if err == nil && found {
+		p.publish(ctx, TaskUpdated, id)
+	}
+
+	return task, found, err
+}
+
+func (p *PublishingStorage) ImportTask(ctx context.Context, task Task) (bool, error) {
+	created, err := p.Storage.ImportTask(ctx, task)
+	if err == nil {
+		eventType := TaskUpdated
+		if created {
+			eventType = TaskCreated
+		}
+		p.publish(ctx, eventType, task.ID)
+	}
+
+	return created, err
+}
+
+func (p *PublishingStorage) DeleteTaskTree(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	ids, err := p.Storage.DeleteTaskTree(ctx, id)
+	for _, deleted := range ids {
+		p.publish(ctx, TaskDeleted, deleted)
+	}
+
+	return ids, err
+}
+
+// AddDependency publishes an update of both tasks, the blocked and blocking numbers change.
+func (p *PublishingStorage) AddDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	err := p.Storage.AddDependency(ctx, id, blockerID)
+	if err == nil {
+		p.publish(ctx, TaskUpdated, id)
+		p.publish(ctx, TaskUpdated, blockerID)
+	}
+
+	return err
+}
+
+// RecurTasks publishes the created occurrences and the updates of the tasks that passed their recurrence on.
+func (p *PublishingStorage) RecurTasks(ctx context.Context, now time.Time) ([]Occurrence, error) {
+	occurrences, err := p.Storage.RecurTasks(ctx, now)
+	for _, occurrence := range occurrences {
+		p.publishOccurrence(occurrence)
+	}
+
+	return occurrences, err
+}
+
+// RecurTask publishes the created occurrence and the update of the task that passed its recurrence on.
+func (p *PublishingStorage) RecurTask(ctx context.Context, id uuid.UUID, now time.Time) (Occurrence, bool, error) {
+	occurrence, recurred, err := p.Storage.RecurTask(ctx, id, now)
+	if err == nil && recurred {
+		p.publishOccurrence(occurrence)
+	}
+
+	return occurrence, recurred, err
+}
+
+// RenameTag publishes a change of the tasks of the user, the tags of their rows change.
+func (p *PublishingStorage) RenameTag(ctx context.Context, id uuid.UUID, name string) (Tag, bool, error) {
+	tag, found, err := p.Storage.RenameTag(ctx, id, name)
+	if err == nil && found {
+		p.publish(ctx, TasksChanged, uuid.Nil)
+	}
+
+	return tag, found, err
+}
+
+// DeleteTag publishes a change of the tasks of the user, the tags of their rows change.
+func (p *PublishingStorage) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	err := p.Storage.DeleteTag(ctx, id)
+	if err == nil {
+		p.publish(ctx, TasksChanged, uuid.Nil)
+	}
+
+	return err
+}
+
+// ClaimTasks publishes a change of the tasks of the user, the claimed tasks appear in the rows.
+func (p *PublishingStorage) ClaimTasks(ctx context.Context, userID uuid.UUID) (int, error) {
+	claimed, err := p.Storage.ClaimTasks(ctx, userID)
+	if err == nil && claimed > 0 {
+		p.hub.Publish(TaskEvent{Type: TasksChanged, OwnerID: userID})
+	}
+
+	return claimed, err
+}
+
+func (p *PublishingStorage) RemoveDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	err := p.Storage.RemoveDependency(ctx, id, blockerID)
+	if err == nil {
+		p.publish(ctx, TaskUpdated, id)
+		p.publish(ctx, TaskUpdated, blockerID)
+	}
+
+	return err
+}
+
+// publish sends the task event to the subscribers of the context user, the events of unscoped changes reach none.
+func (p *PublishingStorage) publish(ctx context.Context, eventType TaskEventType, id uuid.UUID) {
+	user, _ := UserFromContext(ctx)
+	p.hub.Publish(TaskEvent{Type: eventType, ID: id, OwnerID: user.ID})
+}
+
+func (p *PublishingStorage) publishOccurrence(occurrence Occurrence) {
+	p.hub.Publish(TaskEvent{Type: TaskUpdated, ID: occurrence.ID, OwnerID: occurrence.OwnerID})
+	if occurrence.NextID != uuid.Nil {
+		p.hub.Publish(TaskEvent{Type: TaskCreated, ID: occurrence.NextID, OwnerID: occurrence.OwnerID})
+	}
+}
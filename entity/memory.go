@@ -3,6 +3,8 @@ package entity
 import (
 	"cmp"
 	"context"
+	"fmt"
+	"iter"
 	"slices"
 	"strings"
 	"sync"
@@ -14,144 +16,877 @@ import (
 type Memory struct {
 	sync.RWMutex
 
-	tasks map[uuid.UUID]Task
+	tasks     map[uuid.UUID]Task
+	owners    map[uuid.UUID]uuid.UUID   // task owner IDs
+	blockers  map[uuid.UUID][]uuid.UUID // blocker IDs of the tasks
+	tags      map[uuid.UUID]Tag
+	tagOwners map[uuid.UUID]uuid.UUID // tag owner IDs
+	users     map[uuid.UUID]User
+	index     searchIndex
 }
 
 func NewMemory() *Memory {
 	return &Memory{
-		tasks: map[uuid.UUID]Task{},
+		tasks:     map[uuid.UUID]Task{},
+		owners:    map[uuid.UUID]uuid.UUID{},
+		blockers:  map[uuid.UUID][]uuid.UUID{},
+		tags:      map[uuid.UUID]Tag{},
+		tagOwners: map[uuid.UUID]uuid.UUID{},
+		users:     map[uuid.UUID]User{},
+		index:     searchIndex{},
 	}
 }
 
+func (m *Memory) Ping(_ context.Context) error {
+	return nil
+}
+
 func (m *Memory) Close() error {
 	return nil
 }
 
-func (m *Memory) AddTask(_ context.Context, data TaskData) (uuid.UUID, error) {
+func (m *Memory) AddTask(ctx context.Context, data TaskData) (uuid.UUID, error) {
 	m.Lock()
 	defer m.Unlock()
 
+	user, _ := UserFromContext(ctx)
+	tags, err := m.ensureTags(user.ID, data.Tags)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	id := uuid.New()
+	err = m.checkParent(ctx, id, data.ParentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	m.tasks[id] = Task{
 		ID:          id,
 		Subject:     data.Subject,
 		CreatedAt:   time.Now(),
 		DueDate:     data.DueDate,
 		Description: data.Description,
+		Tags:        tags,
+		Version:     1,
+		ParentID:    data.ParentID,
+		Recurrence:  data.Recurrence,
 	}
+	m.owners[id] = user.ID
+	m.index.add(m.tasks[id])
 
 	return id, nil
 }
 
-func (m *Memory) TaskCount(_ context.Context) (int, error) {
+func (m *Memory) TaskCount(ctx context.Context) (int, error) {
 	m.RLock()
 	defer m.RUnlock()
 
-	return len(m.tasks), nil
+	return m.taskCount(ctx), nil
 }
 
-func (m *Memory) Task(_ context.Context, id uuid.UUID) (Task, bool, error) {
+func (m *Memory) Task(ctx context.Context, id uuid.UUID) (Task, bool, error) {
 	m.RLock()
 	defer m.RUnlock()
 
-	t, ok := m.tasks[id]
+	t, ok := m.task(ctx, id)
+	t.Tags = slices.Clone(t.Tags)
 
 	return t, ok, nil
 }
 
-func (m *Memory) Tasks(_ context.Context, query TaskQuery) (TaskPage, error) {
+func (m *Memory) Tasks(ctx context.Context, query TaskQuery) (TaskPage, error) {
 	m.RLock()
 	defer m.RUnlock()
 
-	tasks := []Task{}
+	terms := SearchTerms(query.Search)
+	relevance := m.index.match(terms)
+	tasks := []TaskOverview{}
 	for _, t := range m.tasks {
-		if strings.Contains(t.Subject, query.Filter) {
-			tasks = append(tasks, t)
+		rank, found := relevance[t.ID]
+		if m.owns(ctx, t.ID) && (len(terms) == 0 || found) &&
+			matchStatus(t.Status, query.Statuses) && matchTags(t.Tags, query.Tags) && (!query.Blocked || m.blocked(t.ID)) {
+			overview := t.Overview()
+			overview.Relevance = rank
+			tasks = append(tasks, overview)
 		}
 	}
 
 	slices.SortStableFunc(tasks, taskSortFunc(query.Sort, query.Order))
-	pageStart := (query.Page - 1) * query.Size
-	if pageStart > len(tasks) {
-		return TaskPage{}, nil
-	}
 
 	page := TaskPage{
-		Count:   len(m.tasks),
+		Count:   m.taskCount(ctx),
 		Results: len(tasks),
-		Start:   pageStart,
+		Start:   query.Offset(),
 		Tasks:   []TaskOverview{},
 	}
 
-	pageEnd := min(pageStart+query.Size, len(tasks))
-	for _, t := range tasks[pageStart:pageEnd] {
-		page.Tasks = append(page.Tasks, TaskOverview{
-			ID:        t.ID,
-			CreatedAt: t.CreatedAt,
-			DueDate:   t.DueDate,
-			Subject:   t.Subject,
-		})
+	if page.Start >= len(tasks) {
+		return page, nil
+	}
+
+	pageEnd := min(page.Start+query.Limit(), len(tasks))
+	for _, t := range tasks[page.Start:pageEnd] {
+		overview := m.overview(m.tasks[t.ID])
+		overview.Relevance = t.Relevance
+		page.Tasks = append(page.Tasks, overview)
 	}
 
 	return page, nil
 }
 
-func (m *Memory) DeleteTask(_ context.Context, id uuid.UUID) error {
+func (m *Memory) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	m.Lock()
 	defer m.Unlock()
 
+	t, ok := m.task(ctx, id)
+	if !ok {
+		return fmt.Errorf("no row for %s: %w", id, ErrNotFound)
+	}
+
+	for cid, c := range m.tasks {
+		if c.ParentID == id {
+			c.ParentID = t.ParentID
+			m.tasks[cid] = c
+		}
+	}
+
+	m.index.remove(t)
+	m.removeDependencies(id)
 	delete(m.tasks, id)
+	delete(m.owners, id)
 
 	return nil
 }
 
-func (m *Memory) UpdateTask(_ context.Context, id uuid.UUID, data TaskData) (Task, bool, error) {
+func (m *Memory) UpdateTask(ctx context.Context, id uuid.UUID, data TaskData) (Task, bool, error) {
 	m.Lock()
 	defer m.Unlock()
 
-	t, ok := m.tasks[id]
+	t, ok := m.task(ctx, id)
 	if !ok {
 		return t, false, nil
 	}
 
+	if data.Version != 0 && data.Version != t.Version {
+		t.Tags = slices.Clone(t.Tags)
+
+		return Task{}, true, ConflictError{Task: t}
+	}
+
+	tags, err := m.ensureTags(m.owners[id], data.Tags)
+	if err != nil {
+		return Task{}, false, err
+	}
+
+	m.index.remove(t)
 	t.Subject = data.Subject
+	t.Tags = tags
 	t.DueDate = data.DueDate
 	t.Description = data.Description
+	t.Recurrence = data.Recurrence
+	t.Version++
 	m.tasks[id] = t
+	m.index.add(t)
 
 	return t, true, nil
 }
 
-func taskSortValue(sort TaskSort) func(Task) string {
+func (m *Memory) UpdateTaskStatus(ctx context.Context, id uuid.UUID, status TaskStatus) (Task, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	t, ok := m.task(ctx, id)
+	if !ok {
+		return t, false, nil
+	}
+
+	if !t.Status.CanTransitionTo(status) {
+		t.Tags = slices.Clone(t.Tags)
+
+		return Task{}, true, TransitionError{Task: t, Status: status}
+	}
+
+	now := time.Now()
+	t.Status = status
+	switch status {
+	case TaskStatusOpen:
+		t.DoneAt = nil
+		t.CancelledAt = nil
+	case TaskStatusInProgress:
+		t.StartedAt = &now
+	case TaskStatusDone:
+		t.DoneAt = &now
+	case TaskStatusCancelled:
+		t.CancelledAt = &now
+	}
+	t.Version++
+	m.tasks[id] = t
+
+	return t, true, nil
+}
+
+func (m *Memory) ExportTasks(ctx context.Context) iter.Seq2[Task, error] {
+	m.RLock()
+	tasks := []Task{}
+	depths := map[uuid.UUID]int{}
+	for id, t := range m.tasks {
+		if m.owns(ctx, id) {
+			t.Tags = slices.Clone(t.Tags)
+			tasks = append(tasks, t)
+			depths[id] = m.depth(t)
+		}
+	}
+	m.RUnlock()
+
+	slices.SortFunc(tasks, func(a, b Task) int { // parents before their subtasks
+		return cmp.Or(cmp.Compare(depths[a.ID], depths[b.ID]), a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID.String(), b.ID.String()))
+	})
+
+	return func(yield func(Task, error) bool) {
+		for _, t := range tasks {
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *Memory) ImportTask(ctx context.Context, task Task) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	existing, exists := m.tasks[task.ID]
+	if exists && !m.owns(ctx, task.ID) {
+		return false, fmt.Errorf("task %s of another user: %w", task.ID, ErrNotFound)
+	}
+
+	err := m.checkParent(ctx, task.ID, task.ParentID)
+	if err != nil {
+		return false, err
+	}
+
+	owner := m.owners[task.ID]
+	if !exists {
+		user, _ := UserFromContext(ctx)
+		owner = user.ID
+	}
+
+	tags, err := m.ensureTags(owner, task.Tags)
+	if err != nil {
+		return false, err
+	}
+	task.Tags = tags
+
+	if exists {
+		m.index.remove(existing)
+		task.Version = existing.Version + 1
+	} else {
+		task.Version = max(task.Version, 1)
+		m.owners[task.ID] = owner
+	}
+	m.tasks[task.ID] = task
+	m.index.add(task)
+
+	return !exists, nil
+}
+
+func (m *Memory) Subtasks(ctx context.Context, id uuid.UUID) ([]Subtask, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	subtasks := []Subtask{}
+	var collect func(parent uuid.UUID, depth int)
+	collect = func(parent uuid.UUID, depth int) {
+		for _, t := range m.tasks {
+			if t.ParentID == parent && m.owns(ctx, t.ID) {
+				subtasks = append(subtasks, Subtask{TaskOverview: m.overview(t), ParentID: parent, Depth: depth})
+				collect(t.ID, depth+1)
+			}
+		}
+	}
+
+	if _, ok := m.task(ctx, id); ok {
+		collect(id, 1)
+	}
+
+	return SortSubtasks(id, subtasks), nil
+}
+
+func (m *Memory) Ancestors(ctx context.Context, id uuid.UUID) ([]TaskOverview, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	ancestors := []TaskOverview{}
+	t, ok := m.task(ctx, id)
+	for ok && t.ParentID != uuid.Nil {
+		t, ok = m.task(ctx, t.ParentID)
+		if ok {
+			ancestors = append(ancestors, m.overview(t))
+		}
+	}
+
+	return ancestors, nil
+}
+
+func (m *Memory) DeleteTaskTree(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.task(ctx, id); !ok {
+		return nil, fmt.Errorf("no row for %s: %w", id, ErrNotFound)
+	}
+
+	ids := []uuid.UUID{id}
+	for parents := ids; len(parents) > 0; {
+		var children []uuid.UUID
+		for tid, t := range m.tasks {
+			if slices.Contains(parents, t.ParentID) {
+				children = append(children, tid)
+			}
+		}
+		ids = append(ids, children...)
+		parents = children
+	}
+
+	for _, tid := range ids {
+		m.index.remove(m.tasks[tid])
+		m.removeDependencies(tid)
+		delete(m.tasks, tid)
+		delete(m.owners, tid)
+	}
+
+	return ids, nil
+}
+
+func (m *Memory) AddDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	if id == blockerID {
+		return fmt.Errorf("task %s blocks itself: %w", id, ErrDependencyCycle)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	for _, tid := range []uuid.UUID{id, blockerID} {
+		if _, ok := m.task(ctx, tid); !ok {
+			return fmt.Errorf("no row for %s: %w", tid, ErrNotFound)
+		}
+	}
+
+	if m.dependsOn(blockerID, id) {
+		return fmt.Errorf("task %s depends on %s: %w", blockerID, id, ErrDependencyCycle)
+	}
+
+	if !slices.Contains(m.blockers[id], blockerID) {
+		m.blockers[id] = append(m.blockers[id], blockerID)
+	}
+
+	return nil
+}
+
+func (m *Memory) RemoveDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	m.Lock()
+	defer m.Unlock()
+
+	b := slices.Index(m.blockers[id], blockerID)
+	if _, ok := m.task(ctx, id); !ok || b == -1 {
+		return fmt.Errorf("no dependency of %s on %s: %w", id, blockerID, ErrNotFound)
+	}
+
+	m.blockers[id] = slices.Delete(m.blockers[id], b, b+1)
+
+	return nil
+}
+
+func (m *Memory) Dependencies(ctx context.Context, id uuid.UUID) (TaskDependencies, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	dependencies := TaskDependencies{BlockedBy: []TaskOverview{}, Blocking: []TaskOverview{}}
+	if _, ok := m.task(ctx, id); !ok {
+		return dependencies, nil
+	}
+
+	for _, blocker := range m.blockers[id] {
+		dependencies.BlockedBy = append(dependencies.BlockedBy, m.overview(m.tasks[blocker]))
+	}
+
+	for tid, blockers := range m.blockers {
+		if slices.Contains(blockers, id) {
+			dependencies.Blocking = append(dependencies.Blocking, m.overview(m.tasks[tid]))
+		}
+	}
+
+	byCreation := func(a, b TaskOverview) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID.String(), b.ID.String()))
+	}
+	slices.SortFunc(dependencies.BlockedBy, byCreation)
+	slices.SortFunc(dependencies.Blocking, byCreation)
+
+	return dependencies, nil
+}
+
+func (m *Memory) WorkPlan(ctx context.Context) ([]TaskOverview, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	tasks := []TaskOverview{}
+	blockers := map[uuid.UUID][]uuid.UUID{}
+	for id, t := range m.tasks {
+		if m.owns(ctx, id) && !t.Status.Finished() {
+			tasks = append(tasks, m.overview(t))
+			blockers[id] = m.blockers[id]
+		}
+	}
+
+	return SortWorkPlan(tasks, blockers), nil
+}
+
+func (m *Memory) RecurTasks(ctx context.Context, now time.Time) ([]Occurrence, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	recurring := []Task{}
+	for id, t := range m.tasks {
+		if m.owns(ctx, id) && t.Recurs(now) {
+			recurring = append(recurring, t)
+		}
+	}
+	slices.SortFunc(recurring, func(a, b Task) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID.String(), b.ID.String()))
+	})
+
+	occurrences := make([]Occurrence, 0, len(recurring))
+	for _, t := range recurring {
+		occurrences = append(occurrences, m.recur(t, now))
+	}
+
+	return occurrences, nil
+}
+
+func (m *Memory) RecurTask(ctx context.Context, id uuid.UUID, now time.Time) (Occurrence, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	t, ok := m.task(ctx, id)
+	if !ok || !t.Recurs(now) {
+		return Occurrence{}, false, nil
+	}
+
+	return m.recur(t, now), true, nil
+}
+
+func (m *Memory) AddTag(ctx context.Context, name string) (Tag, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	tag, err := NormalizeTag(name)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	user, _ := UserFromContext(ctx)
+
+	return m.ensureTag(user.ID, tag), nil
+}
+
+func (m *Memory) Tags(ctx context.Context) ([]Tag, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	tags := make([]Tag, 0, len(m.tags))
+	for id, t := range m.tags {
+		if m.ownsTag(ctx, id) {
+			tags = append(tags, t)
+		}
+	}
+
+	slices.SortFunc(tags, func(i, j Tag) int {
+		return cmp.Compare(i.Name, j.Name)
+	})
+
+	return tags, nil
+}
+
+func (m *Memory) RenameTag(ctx context.Context, id uuid.UUID, name string) (Tag, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	tag, ok := m.tags[id]
+	if !ok || !m.ownsTag(ctx, id) {
+		return Tag{}, false, nil
+	}
+
+	renamed, err := NormalizeTag(name)
+	if err != nil {
+		return Tag{}, false, err
+	}
+
+	owner := m.tagOwners[id]
+	if existing, ok := m.tagByName(owner, renamed); ok && existing.ID != id {
+		return Tag{}, false, fmt.Errorf("%w: %s", ErrTagExists, renamed)
+	}
+
+	for tid, t := range m.tasks {
+		if i := slices.Index(t.Tags, tag.Name); i != -1 && m.owners[tid] == owner {
+			t.Tags = slices.Clone(t.Tags)
+			t.Tags[i] = renamed
+			slices.Sort(t.Tags)
+			m.tasks[tid] = t
+		}
+	}
+
+	tag.Name = renamed
+	m.tags[id] = tag
+
+	return tag, true, nil
+}
+
+func (m *Memory) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	m.Lock()
+	defer m.Unlock()
+
+	tag, ok := m.tags[id]
+	if !ok || !m.ownsTag(ctx, id) {
+		return fmt.Errorf("no tag for %s: %w", id, ErrNotFound)
+	}
+
+	owner := m.tagOwners[id]
+	for tid, t := range m.tasks {
+		if i := slices.Index(t.Tags, tag.Name); i != -1 && m.owners[tid] == owner {
+			t.Tags = slices.Delete(slices.Clone(t.Tags), i, i+1)
+			m.tasks[tid] = t
+		}
+	}
+
+	delete(m.tags, id)
+	delete(m.tagOwners, id)
+
+	return nil
+}
+
+func (m *Memory) AddUser(_ context.Context, name, password string) (User, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	normalized, err := NormalizeUserName(name)
+	if err != nil {
+		return User{}, err
+	}
+
+	if _, ok := m.userByName(normalized); ok {
+		return User{}, fmt.Errorf("%w: %s", ErrUserExists, normalized)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	user := User{ID: uuid.New(), Name: normalized, PasswordHash: hash, CreatedAt: time.Now()}
+	m.users[user.ID] = user
+
+	return user, nil
+}
+
+func (m *Memory) User(_ context.Context, id uuid.UUID) (User, bool, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	user, ok := m.users[id]
+
+	return user, ok, nil
+}
+
+func (m *Memory) UserByName(_ context.Context, name string) (User, bool, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	normalized, err := NormalizeUserName(name)
+	if err != nil {
+		return User{}, false, nil // an invalid name is unknown
+	}
+
+	user, ok := m.userByName(normalized)
+
+	return user, ok, nil
+}
+
+func (m *Memory) ClaimTasks(_ context.Context, userID uuid.UUID) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	claimed := 0
+	for id := range m.tasks {
+		if m.owners[id] == uuid.Nil {
+			m.owners[id] = userID
+			claimed++
+		}
+	}
+
+	for id, tag := range m.tags {
+		if m.tagOwners[id] != uuid.Nil {
+			continue
+		}
+
+		if _, ok := m.tagByName(userID, tag.Name); ok {
+			delete(m.tags, id)
+			delete(m.tagOwners, id)
+		} else {
+			m.tagOwners[id] = userID
+		}
+	}
+
+	return claimed, nil
+}
+
+// recur adds the next occurrence of the task and links it or ends the recurrence, the caller holds the lock.
+func (m *Memory) recur(t Task, now time.Time) Occurrence {
+	occurrence := Occurrence{ID: t.ID, OwnerID: m.owners[t.ID]}
+	if data, ok := t.NextOccurrence(now); ok {
+		occurrence.NextID = uuid.New()
+		m.tasks[occurrence.NextID] = Task{
+			ID:          occurrence.NextID,
+			Subject:     data.Subject,
+			CreatedAt:   time.Now(),
+			DueDate:     data.DueDate,
+			Description: data.Description,
+			Tags:        slices.Clone(data.Tags),
+			Version:     1,
+			ParentID:    data.ParentID,
+			Recurrence:  data.Recurrence,
+		}
+		m.owners[occurrence.NextID] = m.owners[t.ID]
+		m.index.add(m.tasks[occurrence.NextID])
+	} else {
+		t.Recurrence = Recurrence{}
+	}
+
+	t.NextID = occurrence.NextID
+	t.Version++
+	m.tasks[t.ID] = t
+
+	return occurrence
+}
+
+// ownsTag reports whether the tag is owned by the context user, all tags without user.
+func (m *Memory) ownsTag(ctx context.Context, id uuid.UUID) bool {
+	user, ok := UserFromContext(ctx)
+
+	return !ok || m.tagOwners[id] == user.ID
+}
+
+// owns reports whether the task is owned by the context user, all tasks without user.
+func (m *Memory) owns(ctx context.Context, id uuid.UUID) bool {
+	user, ok := UserFromContext(ctx)
+
+	return !ok || m.owners[id] == user.ID
+}
+
+func (m *Memory) task(ctx context.Context, id uuid.UUID) (Task, bool) {
+	t, ok := m.tasks[id]
+	if !ok || !m.owns(ctx, id) {
+		return Task{}, false
+	}
+
+	return t, true
+}
+
+// checkParent returns an ErrInvalidParent error if the parent is missing or a subtask of the task, a task without parent is valid.
+func (m *Memory) checkParent(ctx context.Context, id, parentID uuid.UUID) error {
+	if parentID == uuid.Nil {
+		return nil
+	}
+
+	if _, ok := m.task(ctx, parentID); !ok {
+		return fmt.Errorf("parent %s: %w", parentID, ErrInvalidParent)
+	}
+
+	for p := parentID; p != uuid.Nil; p = m.tasks[p].ParentID {
+		if p == id {
+			return fmt.Errorf("parent %s is a subtask of %s: %w", parentID, id, ErrInvalidParent)
+		}
+	}
+
+	return nil
+}
+
+// overview returns the list view of the task with the number of its unfinished dependencies.
+func (m *Memory) overview(t Task) TaskOverview {
+	overview := t.Overview()
+	overview.Tags = slices.Clone(overview.Tags)
+	for _, blocker := range m.blockers[t.ID] {
+		if !m.tasks[blocker].Status.Finished() {
+			overview.Blockers++
+		}
+	}
+
+	if !t.Status.Finished() {
+		for id, blockers := range m.blockers {
+			if slices.Contains(blockers, t.ID) && !m.tasks[id].Status.Finished() {
+				overview.Blocking++
+			}
+		}
+	}
+
+	return overview
+}
+
+// blocked reports whether the task has unfinished blockers.
+func (m *Memory) blocked(id uuid.UUID) bool {
+	return slices.ContainsFunc(m.blockers[id], func(blocker uuid.UUID) bool {
+		return !m.tasks[blocker].Status.Finished()
+	})
+}
+
+// dependsOn reports whether the task is blocked by the blocker, directly or by other tasks.
+func (m *Memory) dependsOn(id, blockerID uuid.UUID) bool {
+	visited := map[uuid.UUID]bool{}
+	var visit func(uuid.UUID) bool
+	visit = func(tid uuid.UUID) bool {
+		if tid == blockerID {
+			return true
+		}
+		if visited[tid] {
+			return false
+		}
+		visited[tid] = true
+
+		return slices.ContainsFunc(m.blockers[tid], visit)
+	}
+
+	return visit(id)
+}
+
+// removeDependencies removes the relations of a deleted task.
+func (m *Memory) removeDependencies(id uuid.UUID) {
+	delete(m.blockers, id)
+	for tid, blockers := range m.blockers {
+		m.blockers[tid] = slices.DeleteFunc(blockers, func(blocker uuid.UUID) bool { return blocker == id })
+	}
+}
+
+// depth returns the number of ancestors of the task.
+func (m *Memory) depth(t Task) int {
+	depth := 0
+	for p := t.ParentID; p != uuid.Nil; p = m.tasks[p].ParentID {
+		depth++
+	}
+
+	return depth
+}
+
+func (m *Memory) taskCount(ctx context.Context) int {
+	count := 0
+	for id := range m.tasks {
+		if m.owns(ctx, id) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (m *Memory) userByName(name string) (User, bool) {
+	for _, u := range m.users {
+		if u.Name == name {
+			return u, true
+		}
+	}
+
+	return User{}, false
+}
+
+func (m *Memory) tagByName(owner uuid.UUID, name string) (Tag, bool) {
+	for id, t := range m.tags {
+		if t.Name == name && m.tagOwners[id] == owner {
+			return t, true
+		}
+	}
+
+	return Tag{}, false
+}
+
+func (m *Memory) ensureTag(owner uuid.UUID, name string) Tag {
+	if tag, ok := m.tagByName(owner, name); ok {
+		return tag
+	}
+
+	tag := Tag{ID: uuid.New(), Name: name}
+	m.tags[tag.ID] = tag
+	m.tagOwners[tag.ID] = owner
+
+	return tag
+}
+
+func (m *Memory) ensureTags(owner uuid.UUID, names []string) ([]string, error) {
+	tags, err := NormalizeTags(names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range tags {
+		m.ensureTag(owner, name)
+	}
+
+	return tags, nil
+}
+
+func matchTags(tags []string, required []string) bool {
+	for _, tag := range required {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchStatus(status TaskStatus, statuses []TaskStatus) bool {
+	return len(statuses) == 0 || slices.Contains(statuses, status)
+}
+
+func taskCompareFunc(sort TaskSort) func(i, j TaskOverview) int {
 	switch sort {
 	case TaskSortCreatedAt:
-		return func(t Task) string {
-			return t.CreatedAt.String()
+		return func(i, j TaskOverview) int {
+			return i.CreatedAt.Compare(j.CreatedAt)
 		}
 	case TaskSortDueDate:
-		return func(t Task) string {
-			return t.DueDate.String()
+		return func(i, j TaskOverview) int {
+			return i.DueDate.Compare(j.DueDate)
 		}
 	case TaskSortSubject:
-		return func(t Task) string {
-			return t.Subject
+		return func(i, j TaskOverview) int {
+			return cmp.Compare(i.Subject, j.Subject)
+		}
+	case TaskSortRelevance:
+		return func(i, j TaskOverview) int {
+			return cmp.Compare(j.Relevance, i.Relevance)
 		}
 	}
 
-	return func(t Task) string {
-		return t.ID.String()
+	return func(_, _ TaskOverview) int {
+		return 0
 	}
 }
 
-func taskSortFunc(sort TaskSort, order SortOrder) func(i, j Task) int {
-	value := taskSortValue(sort)
+// taskSortFunc orders by the sort value and the ID to get a stable paging.
+func taskSortFunc(sort TaskSort, order SortOrder) func(i, j TaskOverview) int {
+	compare := taskCompareFunc(sort)
 	if order == AscendingOrder {
-		return func(i, j Task) int {
-			return cmp.Compare(value(i), value(j))
+		return func(i, j TaskOverview) int {
+			return cmp.Or(compare(i, j), strings.Compare(i.ID.String(), j.ID.String()))
+		}
+	}
+
+	if sort == TaskSortRelevance { // most relevant first in both orders
+		return func(i, j TaskOverview) int {
+			return cmp.Or(compare(i, j), strings.Compare(j.ID.String(), i.ID.String()))
 		}
 	}
 
-	return func(i, j Task) int {
-		return cmp.Compare(value(j), value(i))
+	return func(i, j TaskOverview) int {
+		return cmp.Or(compare(j, i), strings.Compare(j.ID.String(), i.ID.String()))
 	}
 }
@@ -0,0 +1,15 @@
+package entity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/entity/storagetest"
+)
+
+func TestMemory(t *testing.T) {
+	storagetest.Run(t, func(_ context.Context) (entity.Storage, error) {
+		return entity.NewMemory(), nil
+	})
+}
@@ -0,0 +1,337 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Frequency int64
+
+const (
+	FrequencyNone Frequency = iota
+	FrequencyDaily
+	FrequencyWeekly
+	FrequencyMonthly
+)
+
+// recurrencePeriods limits the search of the next occurrence, e.g. of a monthly day 31 every 12 months in February.
+const recurrencePeriods = 10_000
+
+var frequencyKeys = []string{
+	"",
+	"DAILY",
+	"WEEKLY",
+	"MONTHLY",
+}
+
+// weekdayKeys are the RRULE weekdays indexed by time.Weekday.
+var weekdayKeys = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Recurrence is a subset of the RFC 5545 RRULE, e.g. FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=5.
+// The due date of the task is the start of the recurrence.
+type Recurrence struct {
+	Frequency Frequency      // FrequencyNone of a task that doesn't recur
+	Interval  int            // of the frequency, e.g. 2 of every other week
+	Weekdays  []time.Weekday // of a weekly recurrence, the weekday of the due date if empty
+	MonthDays []int          // of a monthly recurrence, negative days count from the end of the month
+	Until     time.Time      // last possible date, zero without limit
+	Count     int            // remaining occurrences including the task, zero without limit
+}
+
+// Occurrence is a recurring task that passed its recurrence on to the next occurrence.
+type Occurrence struct {
+	ID      uuid.UUID
+	NextID  uuid.UUID // uuid.Nil if the recurrence ended
+	OwnerID uuid.UUID // of both tasks, uuid.Nil if unowned
+}
+
+func (f Frequency) String() string {
+	return frequencyKeys[f]
+}
+
+// ParseRecurrence parses the RRULE with an optional "RRULE:" prefix, an empty rule doesn't recur.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	if rule == "" {
+		return Recurrence{}, nil
+	}
+
+	r := Recurrence{Interval: 1}
+	parsed := map[string]bool{}
+	for part := range strings.SplitSeq(rule, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if parsed[name] {
+			return Recurrence{}, fmt.Errorf("duplicate rule part %s", name)
+		}
+		parsed[name] = true
+
+		err := r.parsePart(name, value)
+		if err != nil {
+			return Recurrence{}, err
+		}
+	}
+
+	err := r.validate()
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	return r, nil
+}
+
+// String returns the RRULE without prefix, empty of a task that doesn't recur.
+func (r Recurrence) String() string {
+	if r.IsZero() {
+		return ""
+	}
+
+	parts := []string{"FREQ=" + r.Frequency.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for d, day := range r.Weekdays {
+			days[d] = weekdayKeys[day]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.MonthDays) > 0 {
+		days := make([]string, len(r.MonthDays))
+		for d, day := range r.MonthDays {
+			days[d] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func (r Recurrence) IsZero() bool {
+	return r.Frequency == FrequencyNone
+}
+
+// Scan reads the RRULE of a text column.
+func (r *Recurrence) Scan(src any) error {
+	var rule string
+	switch value := src.(type) {
+	case nil:
+	case string:
+		rule = value
+	case []byte:
+		rule = string(value)
+	default:
+		return fmt.Errorf("unsupported recurrence type %T", src)
+	}
+
+	parsed, err := ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+
+	return nil
+}
+
+// Value stores the RRULE as text, empty of a task that doesn't recur.
+func (r Recurrence) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Recurs reports whether the task passes its recurrence on, it's finished or its due date has passed and it has no next
+// occurrence yet.
+func (t Task) Recurs(now time.Time) bool {
+	if t.Recurrence.IsZero() || t.NextID != uuid.Nil {
+		return false
+	}
+
+	return t.Status.Finished() || !t.DueDate.IsZero() && dateOf(t.DueDate).Before(dateOf(now))
+}
+
+// NextOccurrence returns the data of the next open occurrence, false if the recurrence has ended. The next occurrence
+// follows the due date (today without due date) and skips the missed occurrences before today, they count as passed.
+func (t Task) NextOccurrence(now time.Time) (TaskData, bool) {
+	today := dateOf(now)
+	start := today
+	if !t.DueDate.IsZero() {
+		start = dateOf(t.DueDate)
+	}
+
+	r := t.Recurrence
+	for day := range r.occurrences(start) {
+		if !r.Until.IsZero() && day.After(r.Until) {
+			return TaskData{}, false
+		}
+
+		if t.Recurrence.Count > 0 {
+			r.Count--
+			if r.Count == 0 {
+				return TaskData{}, false
+			}
+		}
+
+		if !day.Before(today) {
+			return TaskData{
+				DueDate:     day,
+				Subject:     t.Subject,
+				Description: t.Description,
+				Tags:        t.Tags,
+				ParentID:    t.ParentID,
+				Recurrence:  r,
+			}, true
+		}
+	}
+
+	return TaskData{}, false
+}
+
+func (r *Recurrence) parsePart(name, value string) error {
+	var err error
+	switch name {
+	case "FREQ":
+		frequency := slices.Index(frequencyKeys, value)
+		if frequency < 1 {
+			return fmt.Errorf("unsupported frequency %q, use DAILY, WEEKLY or MONTHLY", value)
+		}
+		r.Frequency = Frequency(frequency)
+	case "INTERVAL":
+		r.Interval, err = strconv.Atoi(value)
+		if err != nil || r.Interval < 1 {
+			return fmt.Errorf("invalid interval %q", value)
+		}
+	case "BYDAY":
+		for day := range strings.SplitSeq(value, ",") {
+			weekday := slices.Index(weekdayKeys, day)
+			if weekday == -1 {
+				return fmt.Errorf("invalid weekday %q, use MO, TU, WE, TH, FR, SA or SU", day)
+			}
+			r.Weekdays = append(r.Weekdays, time.Weekday(weekday))
+		}
+		slices.SortFunc(r.Weekdays, func(a, b time.Weekday) int { return mondayFirst(a) - mondayFirst(b) })
+		r.Weekdays = slices.Compact(r.Weekdays)
+	case "BYMONTHDAY":
+		for day := range strings.SplitSeq(value, ",") {
+			monthDay, err := strconv.Atoi(day)
+			if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+				return fmt.Errorf("invalid month day %q", day)
+			}
+			r.MonthDays = append(r.MonthDays, monthDay)
+		}
+		slices.Sort(r.MonthDays)
+		r.MonthDays = slices.Compact(r.MonthDays)
+	case "UNTIL":
+		date, _, _ := strings.Cut(value, "T") // the date of a date-time
+		r.Until, err = time.Parse("20060102", date)
+		if err != nil {
+			return fmt.Errorf("invalid until date %q, use YYYYMMDD", value)
+		}
+	case "COUNT":
+		r.Count, err = strconv.Atoi(value)
+		if err != nil || r.Count < 1 {
+			return fmt.Errorf("invalid count %q", value)
+		}
+	default:
+		return fmt.Errorf("unsupported rule part %q, use FREQ, INTERVAL, BYDAY, BYMONTHDAY, UNTIL or COUNT", name)
+	}
+
+	return nil
+}
+
+func (r Recurrence) validate() error {
+	switch {
+	case r.IsZero():
+		return errors.New("missing frequency")
+	case len(r.Weekdays) > 0 && r.Frequency != FrequencyWeekly:
+		return errors.New("BYDAY requires a weekly frequency")
+	case len(r.MonthDays) > 0 && r.Frequency != FrequencyMonthly:
+		return errors.New("BYMONTHDAY requires a monthly frequency")
+	case !r.Until.IsZero() && r.Count > 0:
+		return errors.New("UNTIL and COUNT exclude each other")
+	}
+
+	return nil
+}
+
+// occurrences yields the dates after the start, the start is the first occurrence of the periods.
+func (r Recurrence) occurrences(start time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		for period := range recurrencePeriods {
+			for _, day := range r.periodDays(start, period*r.Interval) {
+				if day.After(start) && !yield(day) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// periodDays returns the ordered dates of the period, the offset counts the days, weeks or months from the start.
+func (r Recurrence) periodDays(start time.Time, offset int) []time.Time {
+	switch r.Frequency {
+	case FrequencyDaily:
+		return []time.Time{start.AddDate(0, 0, offset)}
+	case FrequencyWeekly:
+		monday := start.AddDate(0, 0, 7*offset-mondayFirst(start.Weekday()))
+		weekdays := orDefault(r.Weekdays, start.Weekday())
+		days := make([]time.Time, len(weekdays))
+		for d, weekday := range weekdays {
+			days[d] = monday.AddDate(0, 0, mondayFirst(weekday))
+		}
+
+		return days
+	case FrequencyMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1).Day()
+		days := []time.Time{}
+		for _, monthDay := range orDefault(r.MonthDays, start.Day()) {
+			if monthDay < 0 {
+				monthDay += last + 1
+			}
+			if monthDay >= 1 && monthDay <= last {
+				days = append(days, first.AddDate(0, 0, monthDay-1))
+			}
+		}
+		slices.SortFunc(days, time.Time.Compare)
+
+		return days
+	}
+
+	return nil
+}
+
+// orDefault returns the values, the default value if empty.
+func orDefault[T any](values []T, defaultValue T) []T {
+	if len(values) == 0 {
+		return []T{defaultValue}
+	}
+
+	return values
+}
+
+// mondayFirst returns the index of the weekday in a week that starts on Monday, the RRULE default.
+func mondayFirst(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// dateOf returns the date of the time as UTC midnight, like the due dates.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
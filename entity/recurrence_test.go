@@ -0,0 +1,121 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	for _, c := range []struct {
+		rule, want string
+	}{
+		{"", ""},
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=weekly;byday=fr,mo,mo", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=1,-1", "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=-1,1"},
+		{"FREQ=DAILY;UNTIL=20261231T235959Z", "FREQ=DAILY;UNTIL=20261231"},
+		{"FREQ=DAILY;INTERVAL=1;COUNT=5;", "FREQ=DAILY;COUNT=5"},
+	} {
+		t.Run(c.rule, func(t *testing.T) {
+			r, err := entity.ParseRecurrence(c.rule)
+			if err != nil || r.String() != c.want {
+				t.Errorf("parsed %q with error %v, want %q", r, err, c.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalidRecurrence(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=YEARLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;UNTIL=2026",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;UNTIL=20261231;COUNT=2",
+	} {
+		t.Run(rule, func(t *testing.T) {
+			r, err := entity.ParseRecurrence(rule)
+			if err == nil {
+				t.Errorf("parsed %q, want an error", r)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	for _, c := range []struct {
+		name, rule, due, now string
+		want, wantRule       string // empty if the recurrence ended
+	}{
+		{"daily", "FREQ=DAILY", "2026-10-18", "2026-10-18", "2026-10-19", "FREQ=DAILY"},
+		{"daily without due date", "FREQ=DAILY", "", "2026-10-18", "2026-10-19", "FREQ=DAILY"},
+		{"daily missed", "FREQ=DAILY", "2026-10-10", "2026-10-18", "2026-10-18", "FREQ=DAILY"},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", "2026-10-18", "2026-10-18", "2026-10-21", "FREQ=DAILY;INTERVAL=3"},
+		{"weekly", "FREQ=WEEKLY", "2026-10-18", "2026-10-18", "2026-10-25", "FREQ=WEEKLY"},
+		{"weekly by day", "FREQ=WEEKLY;BYDAY=MO,FR", "2026-10-19", "2026-10-18", "2026-10-23", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"weekly by day next week", "FREQ=WEEKLY;BYDAY=MO,FR", "2026-10-23", "2026-10-18", "2026-10-26", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"weekly interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2026-10-23", "2026-10-18", "2026-11-02", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"monthly", "FREQ=MONTHLY", "2026-10-15", "2026-10-14", "2026-11-15", "FREQ=MONTHLY"},
+		{"monthly by day", "FREQ=MONTHLY;BYMONTHDAY=1,-1", "2026-10-31", "2026-10-18", "2026-11-01", "FREQ=MONTHLY;BYMONTHDAY=-1,1"},
+		{"monthly by last day", "FREQ=MONTHLY;BYMONTHDAY=1,-1", "2026-11-01", "2026-10-18", "2026-11-30", "FREQ=MONTHLY;BYMONTHDAY=-1,1"},
+		{"monthly day 31", "FREQ=MONTHLY;BYMONTHDAY=31", "2026-10-31", "2026-10-18", "2026-12-31", "FREQ=MONTHLY;BYMONTHDAY=31"},
+		{"monthly due day 31", "FREQ=MONTHLY", "2027-01-31", "2027-01-01", "2027-03-31", "FREQ=MONTHLY"},
+		{"until", "FREQ=DAILY;UNTIL=20261020", "2026-10-19", "2026-10-18", "2026-10-20", "FREQ=DAILY;UNTIL=20261020"},
+		{"until passed", "FREQ=DAILY;UNTIL=20261020", "2026-10-20", "2026-10-18", "", ""},
+		{"count", "FREQ=DAILY;COUNT=2", "2026-10-18", "2026-10-18", "2026-10-19", "FREQ=DAILY;COUNT=1"},
+		{"count exhausted", "FREQ=DAILY;COUNT=1", "2026-10-18", "2026-10-18", "", ""},
+		{"count exhausted by missed", "FREQ=DAILY;COUNT=3", "2026-10-10", "2026-10-18", "", ""},
+		{"no day in the periods", "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31", "2027-02-28", "2027-02-01", "", ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := entity.ParseRecurrence(c.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := entity.Task{Subject: c.name, Recurrence: r, Tags: []string{"ops"}}
+			if c.due != "" {
+				task.DueDate = date(t, c.due)
+			}
+
+			next, ok := task.NextOccurrence(date(t, c.now))
+			if c.want == "" {
+				if ok {
+					t.Errorf("next occurrence %s %q, want ended", next.DueDate.Format(time.DateOnly), next.Recurrence)
+				}
+
+				return
+			}
+
+			if !ok || !next.DueDate.Equal(date(t, c.want)) || next.Recurrence.String() != c.wantRule {
+				t.Errorf("next occurrence %s %q found %t, want %s %q", next.DueDate.Format(time.DateOnly), next.Recurrence, ok,
+					c.want, c.wantRule)
+			}
+
+			if next.Subject != task.Subject || len(next.Tags) != 1 {
+				t.Errorf("next occurrence %q with tags %v, want the task data", next.Subject, next.Tags)
+			}
+		})
+	}
+}
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	d, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
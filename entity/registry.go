@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Opener returns a storage of the data source name, e.g. memory:// or postgres://user@localhost/tasks.
+type Opener func(ctx context.Context, dsn string) (Storage, error)
+
+var (
+	openersMu sync.RWMutex
+	openers   = map[string]Opener{}
+)
+
+func init() {
+	Register("memory", func(_ context.Context, _ string) (Storage, error) {
+		return NewMemory(), nil
+	})
+}
+
+// Register makes a storage available by the URL scheme of its data source names, it panics on duplicates.
+func Register(scheme string, opener Opener) {
+	openersMu.Lock()
+	defer openersMu.Unlock()
+
+	if opener == nil {
+		panic("storage opener is nil: " + scheme)
+	}
+
+	if _, dup := openers[scheme]; dup {
+		panic("storage scheme registered twice: " + scheme)
+	}
+
+	openers[scheme] = opener
+}
+
+// Schemes returns the sorted schemes of the registered storages.
+func Schemes() []string {
+	openersMu.RLock()
+	defer openersMu.RUnlock()
+
+	return slices.Sorted(maps.Keys(openers))
+}
+
+// Scheme returns the URL scheme of the data source name.
+func Scheme(dsn string) (string, bool) {
+	scheme, _, ok := strings.Cut(dsn, "://")
+
+	return scheme, ok && scheme != ""
+}
+
+// Open opens the storage that is registered for the scheme of the data source name.
+func Open(ctx context.Context, dsn string) (Storage, error) {
+	scheme, ok := Scheme(dsn)
+	if !ok {
+		return nil, fmt.Errorf("invalid storage DSN, missing the scheme, e.g. %s://", strings.Join(Schemes(), ":// or "))
+	}
+
+	openersMu.RLock()
+	opener, found := openers[scheme]
+	openersMu.RUnlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown storage scheme %q, use %s", scheme, strings.Join(Schemes(), ", "))
+	}
+
+	storage, err := opener(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s storage: %w", scheme, err)
+	}
+
+	return storage, nil
+}
@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// subjectWeight ranks subject matches higher than description matches.
+const subjectWeight = 4
+
+// searchIndex is an inverted index of the weighted word frequencies per task.
+type searchIndex map[string]map[uuid.UUID]float64
+
+// SearchTerms returns the distinct lower case words of a search, each matches as a word prefix.
+func SearchTerms(search string) []string {
+	terms := searchWords(search)
+	slices.Sort(terms)
+
+	return slices.Compact(terms)
+}
+
+// IsSearchRune reports whether the rune is part of a searchable word.
+func IsSearchRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !IsSearchRune(r)
+	})
+}
+
+func (s searchIndex) add(task Task) {
+	s.addWords(task.ID, task.Subject, subjectWeight)
+	s.addWords(task.ID, task.Description, 1)
+}
+
+func (s searchIndex) addWords(id uuid.UUID, text string, weight float64) {
+	for _, word := range searchWords(text) {
+		if s[word] == nil {
+			s[word] = map[uuid.UUID]float64{}
+		}
+		s[word][id] += weight
+	}
+}
+
+func (s searchIndex) remove(task Task) {
+	for _, word := range append(searchWords(task.Subject), searchWords(task.Description)...) {
+		delete(s[word], task.ID)
+		if len(s[word]) == 0 {
+			delete(s, word)
+		}
+	}
+}
+
+// match returns the relevance of the tasks matching all terms.
+func (s searchIndex) match(terms []string) map[uuid.UUID]float64 {
+	var matches map[uuid.UUID]float64
+
+	for _, term := range terms {
+		scores := map[uuid.UUID]float64{}
+		for word, frequencies := range s {
+			if strings.HasPrefix(word, term) {
+				for id, frequency := range frequencies {
+					scores[id] += frequency
+				}
+			}
+		}
+
+		if matches == nil {
+			matches = scores
+
+			continue
+		}
+
+		maps.DeleteFunc(matches, func(id uuid.UUID, _ float64) bool {
+			return scores[id] == 0
+		})
+		for id := range matches {
+			matches[id] += scores[id]
+		}
+	}
+
+	return matches
+}
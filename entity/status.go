@@ -0,0 +1,77 @@
+package entity
+
+import "slices"
+
+type TaskStatus int64
+
+const (
+	TaskStatusOpen TaskStatus = iota
+	TaskStatusInProgress
+	TaskStatusDone
+	TaskStatusCancelled
+	TaskStatusDefault = TaskStatusOpen
+)
+
+var taskStatusKeys = []string{
+	"open",
+	"in-progress",
+	"done",
+	"cancelled",
+}
+
+// taskStatusTransitions lists the allowed follow-up states of each state.
+var taskStatusTransitions = map[TaskStatus][]TaskStatus{
+	TaskStatusOpen:       {TaskStatusInProgress, TaskStatusDone, TaskStatusCancelled},
+	TaskStatusInProgress: {TaskStatusOpen, TaskStatusDone, TaskStatusCancelled},
+	TaskStatusDone:       {TaskStatusOpen},
+	TaskStatusCancelled:  {TaskStatusOpen},
+}
+
+func TaskStatuses() []TaskStatus {
+	return []TaskStatus{TaskStatusOpen, TaskStatusInProgress, TaskStatusDone, TaskStatusCancelled}
+}
+
+func (s TaskStatus) String() string {
+	return taskStatusKeys[s]
+}
+
+// Transitions returns the states that can follow the status.
+func (s TaskStatus) Transitions() []TaskStatus {
+	return taskStatusTransitions[s]
+}
+
+// Finished reports whether the task is done or cancelled, a finished task doesn't block other tasks.
+func (s TaskStatus) Finished() bool {
+	return s == TaskStatusDone || s == TaskStatusCancelled
+}
+
+func (s TaskStatus) CanTransitionTo(next TaskStatus) bool {
+	return slices.Contains(taskStatusTransitions[s], next)
+}
+
+// Sources returns the states that can change to the status.
+func (s TaskStatus) Sources() []TaskStatus {
+	sources := []TaskStatus{}
+	for _, from := range TaskStatuses() {
+		if from.CanTransitionTo(s) {
+			sources = append(sources, from)
+		}
+	}
+
+	return sources
+}
+
+func ParseTaskStatus(status string) (TaskStatus, bool) {
+	s := slices.Index(taskStatusKeys, status)
+	if s == -1 {
+		return TaskStatusDefault, false
+	}
+
+	return TaskStatus(s), true
+}
+
+func TaskStatusOrDefault(status string) TaskStatus {
+	s, _ := ParseTaskStatus(status)
+
+	return s
+}
@@ -2,16 +2,90 @@ package entity
 
 import (
 	"context"
+	"iter"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Storage interface {
+	TaskStorage
+	TaskTreeStorage
+	TaskDependencyStorage
+	TaskRecurrenceStorage
+	TagStorage
+	UserStorage
+
+	// Ping checks the storage access, e.g. the database connection.
+	Ping(ctx context.Context) error
 	Close() error
+}
+
+// TaskStorage operations are scoped to the tasks of the context user, see WithUser.
+type TaskStorage interface {
+	// AddTask returns an ErrInvalidParent error if the parent of the data is missing.
 	AddTask(ctx context.Context, data TaskData) (uuid.UUID, error)
 	TaskCount(ctx context.Context) (int, error)
 	Task(ctx context.Context, id uuid.UUID) (task Task, found bool, err error)
 	Tasks(ctx context.Context, query TaskQuery) (TaskPage, error)
+	// DeleteTask moves the children of the task to its parent.
 	DeleteTask(ctx context.Context, id uuid.UUID) error
+	// UpdateTask returns a ConflictError if the data version is set and outdated.
 	UpdateTask(ctx context.Context, id uuid.UUID, data TaskData) (task Task, found bool, err error)
+	// UpdateTaskStatus returns a TransitionError if the current status can't change to the status.
+	UpdateTaskStatus(ctx context.Context, id uuid.UUID, status TaskStatus) (task Task, found bool, err error)
+	// ExportTasks yields all tasks with all fields ordered by depth and creation, the parents before their subtasks.
+	// It streams the tasks without paging.
+	ExportTasks(ctx context.Context) iter.Seq2[Task, error]
+	// ImportTask inserts or replaces the task of the ID with all fields, an update increments the stored version.
+	// It returns an ErrInvalidParent error if the parent is missing or a subtask of the task.
+	ImportTask(ctx context.Context, task Task) (created bool, err error)
+}
+
+// TaskTreeStorage navigates the subtasks of the parent/child hierarchy, scoped like the TaskStorage.
+type TaskTreeStorage interface {
+	// Subtasks returns all descendants of the task, see SortSubtasks.
+	Subtasks(ctx context.Context, id uuid.UUID) ([]Subtask, error)
+	// Ancestors returns the parents of the task, the direct parent first.
+	Ancestors(ctx context.Context, id uuid.UUID) ([]TaskOverview, error)
+	// DeleteTaskTree deletes the task with all its descendants and returns their IDs.
+	DeleteTaskTree(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+}
+
+// TaskDependencyStorage manages the blocked-by relations of the tasks, scoped like the TaskStorage.
+type TaskDependencyStorage interface {
+	// AddDependency blocks the task by the blocker, it returns an ErrNotFound error if a task is missing
+	// and an ErrDependencyCycle error if the blocker depends on the task.
+	AddDependency(ctx context.Context, id, blockerID uuid.UUID) error
+	// RemoveDependency returns an ErrNotFound error if the task isn't blocked by the blocker.
+	RemoveDependency(ctx context.Context, id, blockerID uuid.UUID) error
+	Dependencies(ctx context.Context, id uuid.UUID) (TaskDependencies, error)
+	// WorkPlan returns the unfinished tasks ordered by their dependencies, see SortWorkPlan.
+	WorkPlan(ctx context.Context) ([]TaskOverview, error)
+}
+
+// TaskRecurrenceStorage generates the occurrences of the recurring tasks, scoped like the TaskStorage.
+type TaskRecurrenceStorage interface {
+	// RecurTasks passes the recurrence of the finished and overdue tasks on to their next occurrences, see Task.Recurs.
+	// A task that passed its recurrence on keeps the rule with the ID of the next occurrence, the rule of an ended
+	// recurrence is removed.
+	RecurTasks(ctx context.Context, now time.Time) ([]Occurrence, error)
+	// RecurTask passes the recurrence of the task on like RecurTasks, it returns false if the task doesn't recur.
+	RecurTask(ctx context.Context, id uuid.UUID, now time.Time) (occurrence Occurrence, recurred bool, err error)
+}
+
+type TagStorage interface {
+	AddTag(ctx context.Context, name string) (Tag, error)
+	Tags(ctx context.Context) ([]Tag, error)
+	RenameTag(ctx context.Context, id uuid.UUID, name string) (tag Tag, found bool, err error)
+	DeleteTag(ctx context.Context, id uuid.UUID) error
+}
+
+type UserStorage interface {
+	// AddUser returns an ErrUserExists error if the normalized name is taken.
+	AddUser(ctx context.Context, name, password string) (User, error)
+	User(ctx context.Context, id uuid.UUID) (user User, found bool, err error)
+	UserByName(ctx context.Context, name string) (user User, found bool, err error)
+	// ClaimTasks assigns the unowned tasks, e.g. of a storage before the users, to the user and returns their number.
+	ClaimTasks(ctx context.Context, userID uuid.UUID) (int, error)
 }
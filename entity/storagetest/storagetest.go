@@ -0,0 +1,1532 @@
+// Package storagetest implements a conformance check for entity.Storage implementations.
+//
+// Plug a storage into a test with a function that opens a fresh and empty instance, Run reports every check as a
+// subtest:
+//
+//	func TestStorage(t *testing.T) {
+//		storagetest.Run(t, func(ctx context.Context) (entity.Storage, error) {
+//			return sqlite3.NewFile(ctx, filepath.Join(t.TempDir(), "tasks.sqlite"))
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+// OpenFunc returns a new and empty storage, it is called once for every check.
+type OpenFunc func(ctx context.Context) (entity.Storage, error)
+
+type check struct {
+	name string
+	run  func(ctx context.Context, storage entity.Storage) error
+}
+
+var checks = []check{
+	{"ping", checkPing},
+	{"add and get task", checkAddTask},
+	{"missing task", checkMissingTask},
+	{"update task", checkUpdateTask},
+	{"update task status", checkUpdateTaskStatus},
+	{"task version", checkTaskVersion},
+	{"delete task", checkDeleteTask},
+	{"subtasks", checkSubtasks},
+	{"dependencies", checkDependencies},
+	{"recurrences", checkRecurrences},
+	{"paging", checkPaging},
+	{"sorting", checkSorting},
+	{"filtering", checkFiltering},
+	{"search", checkSearch},
+	{"checklist", checkChecklist},
+	{"tags", checkTags},
+	{"tag owners", checkTagOwners},
+	{"users", checkUsers},
+	{"ownership", checkOwnership},
+	{"claim tasks", checkClaimTasks},
+	{"export and import", checkTransfer},
+	{"concurrency", checkConcurrency},
+}
+
+// TestStorage runs all checks, each against a fresh storage, and returns the joined check errors.
+func TestStorage(ctx context.Context, open OpenFunc) error {
+	errs := []error{}
+	for _, c := range checks {
+		err := runCheck(ctx, open, c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run runs every check as a subtest of the test, each against a fresh storage.
+func Run(t *testing.T, open OpenFunc) {
+	t.Helper()
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			err := runCheck(t.Context(), open, c)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func runCheck(ctx context.Context, open OpenFunc, c check) error {
+	storage, err := open(ctx)
+	if err != nil {
+		return fmt.Errorf("open failed: %w", err)
+	}
+
+	err = c.run(ctx, storage)
+
+	return errors.Join(err, storage.Close())
+}
+
+func day(offset int) time.Time {
+	return time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, offset)
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format(time.DateOnly) == b.Format(time.DateOnly)
+}
+
+func addTasks(ctx context.Context, storage entity.Storage, data ...entity.TaskData) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(data))
+	for _, d := range data {
+		id, err := storage.AddTask(ctx, d)
+		if err != nil {
+			return ids, fmt.Errorf("add task %q failed: %w", d.Subject, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func mustTask(ctx context.Context, storage entity.Storage, id uuid.UUID) (entity.Task, error) {
+	task, found, err := storage.Task(ctx, id)
+	if err != nil {
+		return task, fmt.Errorf("task %s access failed: %w", id, err)
+	}
+
+	if !found {
+		return task, fmt.Errorf("task %s not found", id)
+	}
+
+	return task, nil
+}
+
+func checkTaskData(task entity.Task, data entity.TaskData) error {
+	tags, err := entity.NormalizeTags(data.Tags)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case task.Subject != data.Subject:
+		return fmt.Errorf("subject %q, want %q", task.Subject, data.Subject)
+	case task.Description != data.Description:
+		return fmt.Errorf("description %q, want %q", task.Description, data.Description)
+	case !sameDay(task.DueDate, data.DueDate):
+		return fmt.Errorf("due date %s, want %s", task.DueDate, data.DueDate)
+	case !slices.Equal(task.Tags, tags):
+		return fmt.Errorf("tags %v, want %v", task.Tags, tags)
+	case task.Recurrence.String() != data.Recurrence.String():
+		return fmt.Errorf("recurrence %q, want %q", task.Recurrence, data.Recurrence)
+	}
+
+	return nil
+}
+
+func checkPing(ctx context.Context, storage entity.Storage) error {
+	return storage.Ping(ctx)
+}
+
+func checkAddTask(ctx context.Context, storage entity.Storage) error {
+	data := entity.TaskData{
+		DueDate:     day(3),
+		Subject:     "add task",
+		Description: "some *markdown*",
+		Tags:        []string{"Ops", "backend", "ops"},
+	}
+
+	ids, err := addTasks(ctx, storage, data)
+	if err != nil {
+		return err
+	}
+
+	task, err := mustTask(ctx, storage, ids[0])
+	if err != nil {
+		return err
+	}
+
+	if task.ID != ids[0] {
+		return fmt.Errorf("id %s, want %s", task.ID, ids[0])
+	}
+
+	if task.Status != entity.TaskStatusOpen {
+		return fmt.Errorf("status %s, want %s", task.Status, entity.TaskStatusOpen)
+	}
+
+	if task.CreatedAt.IsZero() {
+		return errors.New("created at is not set")
+	}
+
+	count, err := storage.TaskCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return fmt.Errorf("count %d, want 1", count)
+	}
+
+	_, err = storage.AddTask(ctx, entity.TaskData{Subject: "invalid tag", Tags: []string{"no spaces"}})
+	if !errors.Is(err, entity.ErrInvalidTag) {
+		return fmt.Errorf("invalid tag error %v, want %v", err, entity.ErrInvalidTag)
+	}
+
+	return checkTaskData(task, data)
+}
+
+func checkMissingTask(ctx context.Context, storage entity.Storage) error {
+	_, found, err := storage.Task(ctx, uuid.New())
+	if err != nil || found {
+		return fmt.Errorf("missing task found %t with error %v, want not found", found, err)
+	}
+
+	return nil
+}
+
+func checkUpdateTask(ctx context.Context, storage entity.Storage) error {
+	ids, err := addTasks(ctx, storage, entity.TaskData{Subject: "update task", Tags: []string{"old"}})
+	if err != nil {
+		return err
+	}
+
+	data := entity.TaskData{
+		DueDate:     day(7),
+		Subject:     "updated task",
+		Description: "updated description",
+		Tags:        []string{"new"},
+	}
+
+	task, found, err := storage.UpdateTask(ctx, ids[0], data)
+	if err != nil || !found {
+		return fmt.Errorf("update found %t with error %v", found, err)
+	}
+
+	err = checkTaskData(task, data)
+	if err != nil {
+		return fmt.Errorf("update result %w", err)
+	}
+
+	task, err = mustTask(ctx, storage, ids[0])
+	if err != nil {
+		return err
+	}
+
+	err = checkTaskData(task, data)
+	if err != nil {
+		return fmt.Errorf("updated task %w", err)
+	}
+
+	_, found, err = storage.UpdateTask(ctx, uuid.New(), data)
+	if err != nil || found {
+		return fmt.Errorf("missing task update found %t with error %v, want not found", found, err)
+	}
+
+	return nil
+}
+
+func checkTaskVersion(ctx context.Context, storage entity.Storage) error {
+	ids, err := addTasks(ctx, storage, entity.TaskData{Subject: "versioned task"})
+	if err != nil {
+		return err
+	}
+
+	task, err := mustTask(ctx, storage, ids[0])
+	if err != nil || task.Version != 1 {
+		return fmt.Errorf("added task version %d with error %v, want 1", task.Version, err)
+	}
+
+	task, _, err = storage.UpdateTask(ctx, ids[0], entity.TaskData{Subject: "first update", Version: 1})
+	if err != nil || task.Version != 2 {
+		return fmt.Errorf("update version %d with error %v, want 2", task.Version, err)
+	}
+
+	task, _, err = storage.UpdateTaskStatus(ctx, ids[0], entity.TaskStatusInProgress)
+	if err != nil || task.Version != 3 {
+		return fmt.Errorf("status update version %d with error %v, want 3", task.Version, err)
+	}
+
+	_, found, err := storage.UpdateTask(ctx, ids[0], entity.TaskData{Subject: "stale update", Version: 2})
+	var conflict entity.ConflictError
+	if !errors.As(err, &conflict) || !found {
+		return fmt.Errorf("stale update found %t with error %v, want conflict", found, err)
+	}
+
+	if conflict.Task.Version != 3 || conflict.Task.Subject != "first update" {
+		return fmt.Errorf("conflict task version %d subject %q, want 3 first update", conflict.Task.Version, conflict.Task.Subject)
+	}
+
+	task, _, err = storage.UpdateTask(ctx, ids[0], entity.TaskData{Subject: "unchecked update"})
+	if err != nil || task.Version != 4 {
+		return fmt.Errorf("unchecked update version %d with error %v, want 4", task.Version, err)
+	}
+
+	_, found, err = storage.UpdateTask(ctx, uuid.New(), entity.TaskData{Subject: "missing", Version: 1})
+	if err != nil || found {
+		return fmt.Errorf("missing task versioned update found %t with error %v, want not found", found, err)
+	}
+
+	return nil
+}
+
+func checkUpdateTaskStatus(ctx context.Context, storage entity.Storage) error {
+	ids, err := addTasks(ctx, storage, entity.TaskData{Subject: "status task"})
+	if err != nil {
+		return err
+	}
+
+	task, found, err := storage.UpdateTaskStatus(ctx, ids[0], entity.TaskStatusInProgress)
+	if err != nil || !found {
+		return fmt.Errorf("start found %t with error %v", found, err)
+	}
+
+	if task.Status != entity.TaskStatusInProgress || task.StartedAt == nil {
+		return fmt.Errorf("started task status %s at %v", task.Status, task.StartedAt)
+	}
+
+	task, found, err = storage.UpdateTaskStatus(ctx, ids[0], entity.TaskStatusDone)
+	if err != nil || !found {
+		return fmt.Errorf("complete found %t with error %v", found, err)
+	}
+
+	if task.Status != entity.TaskStatusDone || task.DoneAt == nil || task.StartedAt == nil {
+		return fmt.Errorf("done task status %s at %v started at %v", task.Status, task.DoneAt, task.StartedAt)
+	}
+
+	task, found, err = storage.UpdateTaskStatus(ctx, ids[0], entity.TaskStatusOpen)
+	if err != nil || !found {
+		return fmt.Errorf("reopen found %t with error %v", found, err)
+	}
+
+	if task.Status != entity.TaskStatusOpen || task.DoneAt != nil || task.CancelledAt != nil {
+		return fmt.Errorf("reopened task status %s done at %v cancelled at %v", task.Status, task.DoneAt, task.CancelledAt)
+	}
+
+	_, found, err = storage.UpdateTaskStatus(ctx, uuid.New(), entity.TaskStatusDone)
+	if err != nil || found {
+		return fmt.Errorf("missing task status update found %t with error %v, want not found", found, err)
+	}
+
+	_, _, err = storage.UpdateTaskStatus(ctx, ids[0], entity.TaskStatusCancelled)
+	if err != nil {
+		return err
+	}
+
+	_, found, err = storage.UpdateTaskStatus(ctx, ids[0], entity.TaskStatusDone)
+	var transition entity.TransitionError
+	if !errors.As(err, &transition) || !found || transition.Task.Status != entity.TaskStatusCancelled {
+		return fmt.Errorf("cancelled to done found %t with error %v, want a transition error", found, err)
+	}
+
+	task, err = mustTask(ctx, storage, ids[0])
+	if err != nil || task.Status != entity.TaskStatusCancelled || task.DoneAt != nil || task.Version != 5 {
+		return fmt.Errorf("rejected transition status %s done at %v version %d with error %v, want unchanged",
+			task.Status, task.DoneAt, task.Version, err)
+	}
+
+	return nil
+}
+
+func checkDeleteTask(ctx context.Context, storage entity.Storage) error {
+	ids, err := addTasks(ctx, storage, entity.TaskData{Subject: "delete me"}, entity.TaskData{Subject: "keep me"})
+	if err != nil {
+		return err
+	}
+
+	err = storage.DeleteTask(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	_, found, err := storage.Task(ctx, ids[0])
+	if err != nil || found {
+		return fmt.Errorf("deleted task found %t with error %v", found, err)
+	}
+
+	count, err := storage.TaskCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return fmt.Errorf("count %d, want 1", count)
+	}
+
+	err = storage.DeleteTask(ctx, ids[0])
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("missing task deletion error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	return nil
+}
+
+func checkSubtasks(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	ids, err := addTasks(aliceCtx, storage, entity.TaskData{Subject: "root"})
+	if err != nil {
+		return err
+	}
+	root := ids[0]
+
+	ids, err = addTasks(aliceCtx, storage, entity.TaskData{Subject: "child", ParentID: root}, entity.TaskData{Subject: "sibling", ParentID: root})
+	if err != nil {
+		return err
+	}
+	child, sibling := ids[0], ids[1]
+
+	ids, err = addTasks(aliceCtx, storage, entity.TaskData{Subject: "grandchild", ParentID: child})
+	if err != nil {
+		return err
+	}
+	grandchild := ids[0]
+
+	task, err := mustTask(aliceCtx, storage, grandchild)
+	if err != nil || task.ParentID != child {
+		return fmt.Errorf("grandchild parent %s with error %v, want %s", task.ParentID, err, child)
+	}
+
+	subtasks, err := storage.Subtasks(aliceCtx, root)
+	if err != nil || len(subtasks) != 3 {
+		return fmt.Errorf("%d subtasks with error %v, want 3", len(subtasks), err)
+	}
+
+	c := slices.IndexFunc(subtasks, func(s entity.Subtask) bool { return s.ID == child })
+	if c == -1 || c == 2 || subtasks[c].Depth != 1 || subtasks[c+1].ID != grandchild ||
+		subtasks[c+1].Depth != 2 || subtasks[c+1].ParentID != child || subtasks[c+1].Subject != "grandchild" {
+		return fmt.Errorf("subtasks %v, want the grandchild at depth 2 after its parent", subtasks)
+	}
+
+	subtasks, err = storage.Subtasks(aliceCtx, sibling)
+	if err != nil || len(subtasks) != 0 {
+		return fmt.Errorf("%d leaf subtasks with error %v, want none", len(subtasks), err)
+	}
+
+	ancestors, err := storage.Ancestors(aliceCtx, grandchild)
+	if err != nil || len(ancestors) != 2 || ancestors[0].ID != child || ancestors[1].ID != root {
+		return fmt.Errorf("ancestors %v with error %v, want the child and the root", ancestors, err)
+	}
+
+	tasks, err := exportTasks(aliceCtx, storage)
+	if err != nil || len(tasks) != 4 || tasks[0].ID != root || tasks[3].ID != grandchild {
+		return fmt.Errorf("export of %d tasks with error %v, want the parents before their subtasks", len(tasks), err)
+	}
+
+	_, err = storage.AddTask(aliceCtx, entity.TaskData{Subject: "orphan", ParentID: uuid.New()})
+	if !errors.Is(err, entity.ErrInvalidParent) {
+		return fmt.Errorf("missing parent error %v, want %v", err, entity.ErrInvalidParent)
+	}
+
+	_, err = storage.AddTask(bobCtx, entity.TaskData{Subject: "intruder", ParentID: root})
+	if !errors.Is(err, entity.ErrInvalidParent) {
+		return fmt.Errorf("other owner parent error %v, want %v", err, entity.ErrInvalidParent)
+	}
+
+	subtasks, err = storage.Subtasks(bobCtx, root)
+	if err != nil || len(subtasks) != 0 {
+		return fmt.Errorf("%d other owner subtasks with error %v, want none", len(subtasks), err)
+	}
+
+	rootTask, err := mustTask(aliceCtx, storage, root)
+	if err != nil {
+		return err
+	}
+
+	rootTask.ParentID = grandchild
+	_, err = storage.ImportTask(aliceCtx, rootTask)
+	if !errors.Is(err, entity.ErrInvalidParent) {
+		return fmt.Errorf("cyclic parent import error %v, want %v", err, entity.ErrInvalidParent)
+	}
+
+	err = storage.DeleteTask(aliceCtx, child)
+	if err != nil {
+		return err
+	}
+
+	task, err = mustTask(aliceCtx, storage, grandchild)
+	if err != nil || task.ParentID != root {
+		return fmt.Errorf("grandchild parent %s after the parent deletion with error %v, want %s", task.ParentID, err, root)
+	}
+
+	_, err = storage.DeleteTaskTree(bobCtx, root)
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("other owner tree deletion error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	deleted, err := storage.DeleteTaskTree(aliceCtx, root)
+	if err != nil || len(deleted) != 3 || !slices.Contains(deleted, root) || !slices.Contains(deleted, grandchild) {
+		return fmt.Errorf("tree deletion of %v with error %v, want the root, the sibling and the grandchild", deleted, err)
+	}
+
+	count, err := storage.TaskCount(aliceCtx)
+	if err != nil || count != 0 {
+		return fmt.Errorf("count %d after the tree deletion with error %v, want 0", count, err)
+	}
+
+	return nil
+}
+
+func checkDependencies(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	ids, err := addTasks(aliceCtx, storage,
+		entity.TaskData{Subject: "design", DueDate: day(1)},
+		entity.TaskData{Subject: "build", DueDate: day(2)},
+		entity.TaskData{Subject: "test", DueDate: day(3)},
+		entity.TaskData{Subject: "deploy"},
+		entity.TaskData{Subject: "docs", DueDate: day(5)},
+	)
+	if err != nil {
+		return err
+	}
+	design, build, test, deploy := ids[0], ids[1], ids[2], ids[3]
+
+	for _, d := range [][2]uuid.UUID{{build, design}, {test, build}, {deploy, test}, {deploy, build}, {build, design}} {
+		err = storage.AddDependency(aliceCtx, d[0], d[1])
+		if err != nil {
+			return fmt.Errorf("dependency of %s on %s: %w", d[0], d[1], err)
+		}
+	}
+
+	err = storage.AddDependency(aliceCtx, design, deploy)
+	if !errors.Is(err, entity.ErrDependencyCycle) {
+		return fmt.Errorf("cyclic dependency error %v, want %v", err, entity.ErrDependencyCycle)
+	}
+
+	err = storage.AddDependency(aliceCtx, design, design)
+	if !errors.Is(err, entity.ErrDependencyCycle) {
+		return fmt.Errorf("self dependency error %v, want %v", err, entity.ErrDependencyCycle)
+	}
+
+	err = storage.AddDependency(aliceCtx, design, uuid.New())
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("missing blocker error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	bobIDs, err := addTasks(bobCtx, storage, entity.TaskData{Subject: "intruder"})
+	if err != nil {
+		return err
+	}
+
+	err = storage.AddDependency(bobCtx, bobIDs[0], design)
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("other owner blocker error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	dependencies, err := storage.Dependencies(aliceCtx, build)
+	blocking := overviewSubjects(dependencies.Blocking)
+	slices.Sort(blocking)
+	if err != nil || len(dependencies.BlockedBy) != 1 || dependencies.BlockedBy[0].ID != design || !slices.Equal(blocking, []string{"deploy", "test"}) {
+		return fmt.Errorf("dependencies %v with error %v, want blocked by the design and blocking the test and the deployment", dependencies, err)
+	}
+
+	page, err := storage.Tasks(aliceCtx, entity.TaskQuery{Blocked: true, Sort: entity.TaskSortSubject, Order: entity.AscendingOrder})
+	if err != nil || !slices.Equal(subjects(page), []string{"build", "deploy", "test"}) {
+		return fmt.Errorf("blocked tasks %v with error %v, want build, deploy and test", subjects(page), err)
+	}
+
+	if page.Tasks[0].Blockers != 1 || page.Tasks[0].Blocking != 2 || page.Tasks[1].Blockers != 2 || page.Tasks[1].Blocking != 0 {
+		return fmt.Errorf("blocked tasks %v, want the number of the blockers and blocked tasks", page.Tasks)
+	}
+
+	plan, err := storage.WorkPlan(aliceCtx)
+	if err != nil || !slices.Equal(overviewSubjects(plan), []string{"design", "build", "test", "docs", "deploy"}) {
+		return fmt.Errorf("work plan %v with error %v, want design, build, test, docs and deploy", overviewSubjects(plan), err)
+	}
+
+	_, _, err = storage.UpdateTaskStatus(aliceCtx, design, entity.TaskStatusDone)
+	if err != nil {
+		return err
+	}
+
+	page, err = storage.Tasks(aliceCtx, entity.TaskQuery{Blocked: true, Sort: entity.TaskSortSubject, Order: entity.AscendingOrder})
+	if err != nil || !slices.Equal(subjects(page), []string{"deploy", "test"}) {
+		return fmt.Errorf("blocked tasks %v after the design is done with error %v, want deploy and test", subjects(page), err)
+	}
+
+	plan, err = storage.WorkPlan(aliceCtx)
+	if err != nil || !slices.Equal(overviewSubjects(plan), []string{"build", "test", "docs", "deploy"}) {
+		return fmt.Errorf("work plan %v after the design is done with error %v, want build, test, docs and deploy", overviewSubjects(plan), err)
+	}
+
+	err = storage.RemoveDependency(aliceCtx, deploy, test)
+	if err != nil {
+		return err
+	}
+
+	err = storage.RemoveDependency(aliceCtx, deploy, test)
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("missing dependency removal error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	err = storage.DeleteTask(aliceCtx, build)
+	if err != nil {
+		return err
+	}
+
+	dependencies, err = storage.Dependencies(aliceCtx, deploy)
+	if err != nil || len(dependencies.BlockedBy) != 0 || len(dependencies.Blocking) != 0 {
+		return fmt.Errorf("dependencies %v after the blocker deletion with error %v, want none", dependencies, err)
+	}
+
+	return nil
+}
+
+func checkRecurrences(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	rule := func(rule string) entity.Recurrence {
+		r, _ := entity.ParseRecurrence(rule)
+
+		return r
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	report := entity.TaskData{Subject: "report", DueDate: day(10), Tags: []string{"work"}, Recurrence: rule("FREQ=WEEKLY;BYDAY=MO,FR")}
+	data := []entity.TaskData{
+		report,
+		{Subject: "standup", DueDate: day(7), Recurrence: rule("FREQ=DAILY;COUNT=5")},
+		{Subject: "review", DueDate: day(20), Recurrence: rule("FREQ=MONTHLY")},
+		{Subject: "retro", DueDate: day(10), Recurrence: rule("FREQ=MONTHLY;COUNT=1")},
+		{Subject: "once", DueDate: day(1)},
+	}
+	ids, err := addTasks(aliceCtx, storage, data...)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		task, err := mustTask(aliceCtx, storage, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkTaskData(task, data[i])
+		if err != nil {
+			return fmt.Errorf("added %q %w", data[i].Subject, err)
+		}
+	}
+
+	_, err = addTasks(bobCtx, storage, entity.TaskData{Subject: "bob chore", DueDate: day(1), Recurrence: rule("FREQ=DAILY")})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range []uuid.UUID{ids[0], ids[3]} {
+		_, _, err = storage.UpdateTaskStatus(aliceCtx, id, entity.TaskStatusDone)
+		if err != nil {
+			return err
+		}
+	}
+
+	// on the Tuesday 11 March the done report moves to Friday, the overdue standup skips two days
+	occurrences, err := storage.RecurTasks(aliceCtx, day(10))
+	if err != nil || len(occurrences) != 3 {
+		return fmt.Errorf("%d occurrences with error %v, want 3", len(occurrences), err)
+	}
+
+	next := map[uuid.UUID]uuid.UUID{}
+	for _, o := range occurrences {
+		next[o.ID] = o.NextID
+	}
+
+	for i, want := range []struct {
+		data    entity.TaskData
+		version int64 // of the passed task, the done report has a status update
+	}{
+		{entity.TaskData{Subject: "report", DueDate: day(13), Tags: []string{"work"}, Recurrence: report.Recurrence}, 3},
+		{entity.TaskData{Subject: "standup", DueDate: day(10), Recurrence: rule("FREQ=DAILY;COUNT=2")}, 2},
+	} {
+		nextID, ok := next[ids[i]]
+		if !ok || nextID == uuid.Nil {
+			return fmt.Errorf("%q next occurrence %s, want a new task", want.data.Subject, nextID)
+		}
+
+		task, err := mustTask(aliceCtx, storage, nextID)
+		if err != nil {
+			return err
+		}
+
+		err = checkTaskData(task, want.data)
+		if err != nil || task.Status != entity.TaskStatusOpen {
+			return fmt.Errorf("%q next occurrence status %s %w", want.data.Subject, task.Status, err)
+		}
+
+		task, err = mustTask(aliceCtx, storage, ids[i])
+		if err != nil || task.Recurrence.String() != data[i].Recurrence.String() || task.NextID != nextID || task.Version != want.version {
+			return fmt.Errorf("%q recurrence %q next %s version %d with error %v, want kept with next %s and version %d",
+				want.data.Subject, task.Recurrence, task.NextID, task.Version, err, nextID, want.version)
+		}
+	}
+
+	nextID, ok := next[ids[3]]
+	if !ok || nextID != uuid.Nil {
+		return fmt.Errorf("retro next occurrence %s, want the end of the recurrence", nextID)
+	}
+
+	task, err := mustTask(aliceCtx, storage, ids[3])
+	if err != nil || !task.Recurrence.IsZero() || task.NextID != uuid.Nil {
+		return fmt.Errorf("ended retro recurrence %q next %s with error %v, want removed", task.Recurrence, task.NextID, err)
+	}
+
+	task, err = mustTask(aliceCtx, storage, ids[2])
+	if err != nil || task.Recurrence.String() != "FREQ=MONTHLY" {
+		return fmt.Errorf("upcoming review recurrence %q with error %v, want unchanged", task.Recurrence, err)
+	}
+
+	occurrences, err = storage.RecurTasks(aliceCtx, day(10))
+	if err != nil || len(occurrences) != 0 {
+		return fmt.Errorf("%d repeated occurrences with error %v, want none", len(occurrences), err)
+	}
+
+	occurrences, err = storage.RecurTasks(bobCtx, day(10))
+	if err != nil || len(occurrences) != 1 {
+		return fmt.Errorf("%d other owner occurrences with error %v, want 1", len(occurrences), err)
+	}
+
+	if occurrences[0].OwnerID != bob.ID {
+		return fmt.Errorf("other owner occurrence owner %s, want %s", occurrences[0].OwnerID, bob.ID)
+	}
+
+	_, found, err := storage.Task(aliceCtx, occurrences[0].NextID)
+	if err != nil || found {
+		return fmt.Errorf("other owner occurrence found %t with error %v, want not found", found, err)
+	}
+
+	task, err = mustTask(bobCtx, storage, occurrences[0].NextID)
+	if err != nil || !sameDay(task.DueDate, day(10)) {
+		return fmt.Errorf("other owner occurrence due %s with error %v, want %s", task.DueDate, err, day(10))
+	}
+
+	for _, id := range []uuid.UUID{ids[0], ids[2], ids[4]} { // passed on, upcoming and without recurrence
+		_, recurred, err := storage.RecurTask(aliceCtx, id, day(10))
+		if err != nil || recurred {
+			return fmt.Errorf("task %s recurred %t with error %v, want no recurrence", id, recurred, err)
+		}
+	}
+
+	_, _, err = storage.UpdateTaskStatus(aliceCtx, ids[2], entity.TaskStatusDone)
+	if err != nil {
+		return err
+	}
+
+	_, recurred, err := storage.RecurTask(bobCtx, ids[2], day(10))
+	if err != nil || recurred {
+		return fmt.Errorf("other owner task recurred %t with error %v, want no recurrence", recurred, err)
+	}
+
+	occurrence, recurred, err := storage.RecurTask(aliceCtx, ids[2], day(10))
+	if err != nil || !recurred || occurrence.ID != ids[2] || occurrence.NextID == uuid.Nil || occurrence.OwnerID != alice.ID {
+		return fmt.Errorf("done review occurrence %+v recurred %t with error %v, want a next occurrence", occurrence, recurred, err)
+	}
+
+	task, err = mustTask(aliceCtx, storage, occurrence.NextID)
+	if err != nil || task.Subject != "review" || task.Recurrence.String() != "FREQ=MONTHLY" {
+		return fmt.Errorf("review next occurrence %q recurrence %q with error %v", task.Subject, task.Recurrence, err)
+	}
+
+	task, err = mustTask(aliceCtx, storage, ids[0])
+	if err != nil {
+		return err
+	}
+
+	data[0].Recurrence = rule("FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=1,-1;UNTIL=20251231")
+	data[0].Version = task.Version
+	task, found, err = storage.UpdateTask(aliceCtx, ids[0], data[0])
+	if err != nil || !found {
+		return fmt.Errorf("recurrence update found %t with error %v", found, err)
+	}
+
+	return checkTaskData(task, data[0])
+}
+
+// overviewSubjects returns the subjects in the order of the tasks, see subjects.
+func overviewSubjects(tasks []entity.TaskOverview) []string {
+	list := make([]string, len(tasks))
+	for t, task := range tasks {
+		list[t] = task.Subject
+	}
+
+	return list
+}
+
+func checkPaging(ctx context.Context, storage entity.Storage) error {
+	const total = 25
+
+	data := make([]entity.TaskData, total)
+	for i := range data {
+		data[i] = entity.TaskData{DueDate: day(i), Subject: fmt.Sprintf("page task %02d", i)}
+	}
+
+	_, err := addTasks(ctx, storage, data...)
+	if err != nil {
+		return err
+	}
+
+	pages := []struct {
+		page, size, start, tasks int
+	}{
+		{page: 1, size: 10, start: 0, tasks: 10},
+		{page: 3, size: 10, start: 20, tasks: 5},
+		{page: 4, size: 10, start: 30, tasks: 0},
+		{page: 2, size: 25, start: 25, tasks: 0},
+		{page: 0, size: 10, start: 0, tasks: 10},
+		{page: 1, size: 0, start: 0, tasks: entity.TaskPageDefaultSize},
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, p := range pages {
+		query := entity.TaskQuery{Page: p.page, Size: p.size, Sort: entity.TaskSortDueDate, Order: entity.AscendingOrder}
+
+		page, err := storage.Tasks(ctx, query)
+		if err != nil {
+			return fmt.Errorf("page %d size %d: %w", p.page, p.size, err)
+		}
+
+		if page.Count != total || page.Results != total || page.Start != p.start || len(page.Tasks) != p.tasks {
+			return fmt.Errorf("page %d size %d count %d results %d start %d tasks %d, want %d %d %d %d",
+				p.page, p.size, page.Count, page.Results, page.Start, len(page.Tasks), total, total, p.start, p.tasks)
+		}
+
+		if page.Tasks == nil {
+			return fmt.Errorf("page %d size %d tasks are nil, want empty", p.page, p.size)
+		}
+
+		if p.page == 1 && p.size == 10 || p.page == 3 {
+			for t, task := range page.Tasks {
+				if seen[task.ID] {
+					return fmt.Errorf("page %d task %s listed twice", p.page, task.ID)
+				}
+				seen[task.ID] = true
+
+				want := fmt.Sprintf("page task %02d", p.start+t)
+				if task.Subject != want {
+					return fmt.Errorf("page %d task %d subject %q, want %q", p.page, t, task.Subject, want)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkSorting(ctx context.Context, storage entity.Storage) error {
+	subjects := []string{"delta", "alpha", "echo", "charlie", "bravo"}
+	data := make([]entity.TaskData, len(subjects))
+	for i, subject := range subjects {
+		data[i] = entity.TaskData{DueDate: day(len(subjects) - i), Subject: subject}
+	}
+
+	_, err := addTasks(ctx, storage, data...)
+	if err != nil {
+		return err
+	}
+
+	compare := map[entity.TaskSort]func(a, b entity.TaskOverview) int{
+		entity.TaskSortCreatedAt: func(a, b entity.TaskOverview) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		entity.TaskSortDueDate:   func(a, b entity.TaskOverview) int { return a.DueDate.Compare(b.DueDate) },
+		entity.TaskSortSubject:   func(a, b entity.TaskOverview) int { return strings.Compare(a.Subject, b.Subject) },
+	}
+
+	for sort, compareFunc := range compare {
+		for _, order := range []entity.SortOrder{entity.AscendingOrder, entity.DescendingOrder} {
+			page, err := storage.Tasks(ctx, entity.TaskQuery{Page: 1, Size: 10, Sort: sort, Order: order})
+			if err != nil {
+				return fmt.Errorf("sort %s %s: %w", sort, order, err)
+			}
+
+			sorted := slices.IsSortedFunc(page.Tasks, func(a, b entity.TaskOverview) int {
+				if order == entity.DescendingOrder {
+					return compareFunc(b, a)
+				}
+
+				return compareFunc(a, b)
+			})
+			if !sorted || len(page.Tasks) != len(subjects) {
+				return fmt.Errorf("sort %s %s returned %d unsorted tasks", sort, order, len(page.Tasks))
+			}
+		}
+	}
+
+	return nil
+}
+
+func subjects(page entity.TaskPage) []string {
+	list := make([]string, len(page.Tasks))
+	for t, task := range page.Tasks {
+		list[t] = task.Subject
+	}
+
+	slices.Sort(list)
+
+	return list
+}
+
+func checkFiltering(ctx context.Context, storage entity.Storage) error {
+	ids, err := addTasks(ctx, storage,
+		entity.TaskData{Subject: "Deploy backend", Tags: []string{"backend", "ops"}},
+		entity.TaskData{Subject: "deploy frontend", Tags: []string{"ops"}},
+		entity.TaskData{Subject: "write report", Tags: []string{"urgent"}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = storage.UpdateTaskStatus(ctx, ids[2], entity.TaskStatusDone)
+	if err != nil {
+		return err
+	}
+
+	queries := []struct {
+		name  string
+		query entity.TaskQuery
+		want  []string
+	}{
+		{"subject", entity.TaskQuery{Search: "deploy"}, []string{"Deploy backend", "deploy frontend"}},
+		{"no match", entity.TaskQuery{Search: "nothing"}, []string{}},
+		{"status", entity.TaskQuery{Statuses: []entity.TaskStatus{entity.TaskStatusDone}}, []string{"write report"}},
+		{"statuses", entity.TaskQuery{Statuses: []entity.TaskStatus{entity.TaskStatusOpen, entity.TaskStatusDone}},
+			[]string{"Deploy backend", "deploy frontend", "write report"}},
+		{"tag", entity.TaskQuery{Tags: []string{"ops"}}, []string{"Deploy backend", "deploy frontend"}},
+		{"tags", entity.TaskQuery{Tags: []string{"ops", "backend"}}, []string{"Deploy backend"}},
+		{"combined", entity.TaskQuery{Search: "front", Tags: []string{"ops"}}, []string{"deploy frontend"}},
+	}
+
+	for _, q := range queries {
+		q.query.Page = 1
+		q.query.Size = 10
+
+		page, err := storage.Tasks(ctx, q.query)
+		if err != nil {
+			return fmt.Errorf("filter %s: %w", q.name, err)
+		}
+
+		got := subjects(page)
+		if !slices.Equal(got, q.want) || page.Results != len(q.want) || page.Count != len(ids) {
+			return fmt.Errorf("filter %s results %d of %d %v, want %v", q.name, page.Results, page.Count, got, q.want)
+		}
+	}
+
+	return nil
+}
+
+func checkChecklist(ctx context.Context, storage entity.Storage) error {
+	_, err := addTasks(ctx, storage,
+		entity.TaskData{Subject: "checklist", Description: "steps:\n\n- [x] plan\n- [ ] build\n  - [X] test\n\n```\n- [ ] code\n```"},
+		entity.TaskData{Subject: "plain list", Description: "- foo\n- bar"},
+	)
+	if err != nil {
+		return err
+	}
+
+	page, err := storage.Tasks(ctx, entity.TaskQuery{Page: 1, Size: 10, Sort: entity.TaskSortSubject})
+	if err != nil {
+		return err
+	}
+
+	want := map[string]entity.Checklist{"checklist": {Done: 2, Total: 3}, "plain list": {}}
+	for _, task := range page.Tasks {
+		if task.Checklist != want[task.Subject] {
+			return fmt.Errorf("task %q checklist %v, want %v", task.Subject, task.Checklist, want[task.Subject])
+		}
+	}
+
+	return nil
+}
+
+func checkSearch(ctx context.Context, storage entity.Storage) error {
+	ids, err := addTasks(ctx, storage,
+		entity.TaskData{Subject: "Release notes", Description: "collect the **changes** of the deployment"},
+		entity.TaskData{Subject: "Deployment pipeline", Description: "speed up the release build"},
+		entity.TaskData{Subject: "Team lunch", Description: "book a table"},
+	)
+	if err != nil {
+		return err
+	}
+
+	queries := []struct {
+		name  string
+		query entity.TaskQuery
+		want  []string
+	}{
+		{"description", entity.TaskQuery{Search: "changes"}, []string{"Release notes"}},
+		{"prefix", entity.TaskQuery{Search: "deploy"}, []string{"Deployment pipeline", "Release notes"}},
+		{"case", entity.TaskQuery{Search: "TEAM"}, []string{"Team lunch"}},
+		{"all terms", entity.TaskQuery{Search: "release build"}, []string{"Deployment pipeline"}},
+		{"punctuation", entity.TaskQuery{Search: `"lunch*" (table)`}, []string{"Team lunch"}},
+		{"no match", entity.TaskQuery{Search: "dinner"}, []string{}},
+		{"ranked", entity.TaskQuery{Search: "deployment", Sort: entity.TaskSortRelevance}, []string{"Deployment pipeline", "Release notes"}},
+	}
+
+	for _, q := range queries {
+		q.query.Page = 1
+		q.query.Size = 10
+
+		page, err := storage.Tasks(ctx, q.query)
+		if err != nil {
+			return fmt.Errorf("search %s: %w", q.name, err)
+		}
+
+		got := subjects(page)
+		if q.query.Sort == entity.TaskSortRelevance {
+			got = rankedSubjects(page)
+		}
+
+		if !slices.Equal(got, q.want) || page.Results != len(q.want) {
+			return fmt.Errorf("search %s results %d %v, want %v", q.name, page.Results, got, q.want)
+		}
+	}
+
+	_, _, err = storage.UpdateTask(ctx, ids[2], entity.TaskData{Subject: "Team dinner"})
+	if err != nil {
+		return err
+	}
+
+	err = storage.DeleteTask(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	for search, want := range map[string][]string{"lunch": {}, "dinner": {"Team dinner"}, "changes": {}} {
+		page, err := storage.Tasks(ctx, entity.TaskQuery{Page: 1, Size: 10, Search: search})
+		if err != nil {
+			return fmt.Errorf("search %s after changes: %w", search, err)
+		}
+
+		if got := subjects(page); !slices.Equal(got, want) {
+			return fmt.Errorf("search %s after changes %v, want %v", search, got, want)
+		}
+	}
+
+	return nil
+}
+
+func rankedSubjects(page entity.TaskPage) []string {
+	list := make([]string, len(page.Tasks))
+	for t, task := range page.Tasks {
+		list[t] = task.Subject
+		if t > 0 && task.Relevance > page.Tasks[t-1].Relevance {
+			list[t] += " (ranked higher than the previous)"
+		}
+	}
+
+	return list
+}
+
+func checkTags(ctx context.Context, storage entity.Storage) error {
+	ops, err := storage.AddTag(ctx, "ops")
+	if err != nil {
+		return err
+	}
+
+	again, err := storage.AddTag(ctx, " OPS ")
+	if err != nil || again.ID != ops.ID {
+		return fmt.Errorf("add existing tag %v with error %v, want %v", again, err, ops)
+	}
+
+	ids, err := addTasks(ctx, storage, entity.TaskData{Subject: "tagged", Tags: []string{"backend", "ops"}})
+	if err != nil {
+		return err
+	}
+
+	tags, err := storage.Tags(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) != 2 || tags[0].Name != "backend" || tags[1].Name != "ops" {
+		return fmt.Errorf("tags %v, want [backend ops]", tags)
+	}
+
+	_, _, err = storage.RenameTag(ctx, ops.ID, "backend")
+	if !errors.Is(err, entity.ErrTagExists) {
+		return fmt.Errorf("rename conflict error %v, want %v", err, entity.ErrTagExists)
+	}
+
+	_, found, err := storage.RenameTag(ctx, uuid.New(), "missing")
+	if err != nil || found {
+		return fmt.Errorf("missing tag rename found %t with error %v, want not found", found, err)
+	}
+
+	renamed, found, err := storage.RenameTag(ctx, ops.ID, "devops")
+	if err != nil || !found || renamed.Name != "devops" {
+		return fmt.Errorf("rename %v found %t with error %v", renamed, found, err)
+	}
+
+	err = storage.DeleteTag(ctx, tags[0].ID)
+	if err != nil {
+		return err
+	}
+
+	err = storage.DeleteTag(ctx, tags[0].ID)
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("missing tag deletion error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	task, err := mustTask(ctx, storage, ids[0])
+	if err != nil {
+		return err
+	}
+
+	if !slices.Equal(task.Tags, []string{"devops"}) {
+		return fmt.Errorf("task tags %v, want [devops]", task.Tags)
+	}
+
+	return nil
+}
+
+func checkTagOwners(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	aliceIDs, err := addTasks(aliceCtx, storage, entity.TaskData{Subject: "alice task", Tags: []string{"ops"}})
+	if err != nil {
+		return err
+	}
+
+	bobIDs, err := addTasks(bobCtx, storage, entity.TaskData{Subject: "bob task", Tags: []string{"ops", "review"}})
+	if err != nil {
+		return err
+	}
+
+	aliceTags, err := storage.Tags(aliceCtx)
+	if err != nil || len(aliceTags) != 1 || aliceTags[0].Name != "ops" {
+		return fmt.Errorf("alice tags %v with error %v, want [ops]", aliceTags, err)
+	}
+
+	_, found, err := storage.RenameTag(bobCtx, aliceTags[0].ID, "devops")
+	if err != nil || found {
+		return fmt.Errorf("other owner tag rename found %t with error %v, want not found", found, err)
+	}
+
+	err = storage.DeleteTag(bobCtx, aliceTags[0].ID)
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("other owner tag deletion error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	bobTags, err := storage.Tags(bobCtx)
+	if err != nil || len(bobTags) != 2 || bobTags[0].ID == aliceTags[0].ID {
+		return fmt.Errorf("bob tags %v with error %v, want own [ops review]", bobTags, err)
+	}
+
+	_, _, err = storage.RenameTag(bobCtx, bobTags[0].ID, "devops")
+	if err != nil {
+		return err
+	}
+
+	err = storage.DeleteTag(bobCtx, bobTags[1].ID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range []struct {
+		ctx  context.Context
+		id   uuid.UUID
+		tags []string
+	}{{aliceCtx, aliceIDs[0], []string{"ops"}}, {bobCtx, bobIDs[0], []string{"devops"}}} {
+		task, err := mustTask(c.ctx, storage, c.id)
+		if err != nil || !slices.Equal(task.Tags, c.tags) {
+			return fmt.Errorf("task %q tags %v with error %v, want %v", task.Subject, task.Tags, err, c.tags)
+		}
+	}
+
+	_, err = addTasks(ctx, storage, entity.TaskData{Subject: "unowned task", Tags: []string{"devops", "triage"}})
+	if err != nil {
+		return err
+	}
+
+	_, err = storage.ClaimTasks(ctx, bob.ID)
+	if err != nil {
+		return err
+	}
+
+	bobTags, err = storage.Tags(bobCtx)
+	if err != nil || len(bobTags) != 2 || bobTags[0].Name != "devops" || bobTags[1].Name != "triage" {
+		return fmt.Errorf("claimed tags %v with error %v, want [devops triage]", bobTags, err)
+	}
+
+	page, err := storage.Tasks(bobCtx, entity.TaskQuery{Page: 1, Size: 10, Tags: []string{"devops"}})
+	if err != nil || page.Results != 2 {
+		return fmt.Errorf("%d devops tasks with error %v, want 2", page.Results, err)
+	}
+
+	return nil
+}
+
+func checkUsers(ctx context.Context, storage entity.Storage) error {
+	user, err := storage.AddUser(ctx, " Alice ", "correct horse")
+	if err != nil {
+		return err
+	}
+
+	if user.Name != "alice" || !user.CheckPassword("correct horse") || user.CheckPassword("wrong horse") {
+		return fmt.Errorf("added user %q with password check failure", user.Name)
+	}
+
+	_, err = storage.AddUser(ctx, "ALICE", "another password")
+	if !errors.Is(err, entity.ErrUserExists) {
+		return fmt.Errorf("add existing user error %v, want %v", err, entity.ErrUserExists)
+	}
+
+	_, err = storage.AddUser(ctx, "bob", "short")
+	if !errors.Is(err, entity.ErrInvalidPassword) {
+		return fmt.Errorf("short password error %v, want %v", err, entity.ErrInvalidPassword)
+	}
+
+	found, ok, err := storage.UserByName(ctx, "alice")
+	if err != nil || !ok || found.ID != user.ID || !found.CheckPassword("correct horse") {
+		return fmt.Errorf("user by name %v found %t with error %v", found, ok, err)
+	}
+
+	found, ok, err = storage.User(ctx, user.ID)
+	if err != nil || !ok || found.Name != "alice" {
+		return fmt.Errorf("user by ID %v found %t with error %v", found, ok, err)
+	}
+
+	for _, name := range []string{"bob", "no spaces"} {
+		_, ok, err = storage.UserByName(ctx, name)
+		if err != nil || ok {
+			return fmt.Errorf("missing user %q found %t with error %v", name, ok, err)
+		}
+	}
+
+	_, ok, err = storage.User(ctx, uuid.New())
+	if err != nil || ok {
+		return fmt.Errorf("missing user ID found %t with error %v", ok, err)
+	}
+
+	return nil
+}
+
+func checkOwnership(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	ids, err := addTasks(aliceCtx, storage, entity.TaskData{Subject: "alice task"}, entity.TaskData{Subject: "alice report"})
+	if err != nil {
+		return err
+	}
+
+	_, err = addTasks(bobCtx, storage, entity.TaskData{Subject: "bob task"})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range []struct {
+		ctx            context.Context
+		count, matches int
+	}{{aliceCtx, 2, 1}, {bobCtx, 1, 1}, {ctx, 3, 2}} {
+		count, err := storage.TaskCount(c.ctx)
+		if err != nil || count != c.count {
+			return fmt.Errorf("count %d with error %v, want %d", count, err, c.count)
+		}
+
+		page, err := storage.Tasks(c.ctx, entity.TaskQuery{Page: 1, Size: 10, Search: "task"})
+		if err != nil || page.Count != c.count || page.Results != c.matches {
+			return fmt.Errorf("page count %d results %d with error %v, want %d %d", page.Count, page.Results, err, c.count, c.matches)
+		}
+	}
+
+	_, found, err := storage.Task(bobCtx, ids[0])
+	if err != nil || found {
+		return fmt.Errorf("other owner task found %t with error %v, want not found", found, err)
+	}
+
+	_, found, err = storage.UpdateTask(bobCtx, ids[0], entity.TaskData{Subject: "taken over", Version: 1})
+	if err != nil || found {
+		return fmt.Errorf("other owner update found %t with error %v, want not found", found, err)
+	}
+
+	_, found, err = storage.UpdateTaskStatus(bobCtx, ids[0], entity.TaskStatusDone)
+	if err != nil || found {
+		return fmt.Errorf("other owner status update found %t with error %v, want not found", found, err)
+	}
+
+	err = storage.DeleteTask(bobCtx, ids[0])
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("other owner deletion error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	task, err := mustTask(aliceCtx, storage, ids[0])
+	if err != nil || task.Subject != "alice task" || task.Status != entity.TaskStatusOpen {
+		return fmt.Errorf("owner task %q status %s with error %v", task.Subject, task.Status, err)
+	}
+
+	return nil
+}
+
+func checkClaimTasks(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	ids, err := addTasks(ctx, storage, entity.TaskData{Subject: "unowned task"}, entity.TaskData{Subject: "unowned report"})
+	if err != nil {
+		return err
+	}
+
+	_, err = addTasks(aliceCtx, storage, entity.TaskData{Subject: "alice task"})
+	if err != nil {
+		return err
+	}
+
+	claimed, err := storage.ClaimTasks(ctx, bob.ID)
+	if err != nil || claimed != 2 {
+		return fmt.Errorf("%d claimed tasks with error %v, want 2", claimed, err)
+	}
+
+	for _, c := range []struct {
+		ctx   context.Context
+		count int
+	}{{aliceCtx, 1}, {bobCtx, 2}, {ctx, 3}} {
+		count, err := storage.TaskCount(c.ctx)
+		if err != nil || count != c.count {
+			return fmt.Errorf("count %d with error %v, want %d", count, err, c.count)
+		}
+	}
+
+	task, err := mustTask(bobCtx, storage, ids[0])
+	if err != nil || task.Subject != "unowned task" || task.Version != 1 {
+		return fmt.Errorf("claimed task %q version %d with error %v", task.Subject, task.Version, err)
+	}
+
+	claimed, err = storage.ClaimTasks(ctx, alice.ID)
+	if err != nil || claimed != 0 {
+		return fmt.Errorf("%d claimed owned tasks with error %v, want none", claimed, err)
+	}
+
+	return nil
+}
+
+func exportTasks(ctx context.Context, storage entity.Storage) ([]entity.Task, error) {
+	tasks := []entity.Task{}
+	for task, err := range storage.ExportTasks(ctx) {
+		if err != nil {
+			return tasks, fmt.Errorf("export failed: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+func checkTransfer(ctx context.Context, storage entity.Storage) error {
+	alice, err := storage.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		return err
+	}
+
+	bob, err := storage.AddUser(ctx, "bob", "bob password")
+	if err != nil {
+		return err
+	}
+
+	aliceCtx, bobCtx := entity.WithUser(ctx, alice), entity.WithUser(ctx, bob)
+	ids, err := addTasks(aliceCtx, storage, entity.TaskData{Subject: "first", Tags: []string{"ops"}})
+	if err != nil {
+		return err
+	}
+
+	done := day(-1)
+	imported := entity.Task{
+		ID:          uuid.New(),
+		CreatedAt:   day(-10),
+		DueDate:     day(3),
+		Subject:     "imported task",
+		Description: "with **all** fields",
+		Status:      entity.TaskStatusDone,
+		DoneAt:      &done,
+		Tags:        []string{"Backend", "ops"},
+		Version:     5,
+	}
+
+	created, err := storage.ImportTask(aliceCtx, imported)
+	if err != nil || !created {
+		return fmt.Errorf("import created %t with error %v, want created", created, err)
+	}
+
+	task, err := mustTask(aliceCtx, storage, imported.ID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !task.CreatedAt.Equal(imported.CreatedAt):
+		return fmt.Errorf("imported created at %s, want %s", task.CreatedAt, imported.CreatedAt)
+	case task.Status != entity.TaskStatusDone || task.DoneAt == nil || !task.DoneAt.Equal(done):
+		return fmt.Errorf("imported status %s done at %v, want done at %s", task.Status, task.DoneAt, done)
+	case task.Version != 5:
+		return fmt.Errorf("imported version %d, want 5", task.Version)
+	case !slices.Equal(task.Tags, []string{"backend", "ops"}):
+		return fmt.Errorf("imported tags %v, want [backend ops]", task.Tags)
+	}
+
+	tasks, err := exportTasks(aliceCtx, storage)
+	if err != nil {
+		return err
+	}
+
+	if len(tasks) != 2 || tasks[0].ID != imported.ID || tasks[1].ID != ids[0] {
+		return fmt.Errorf("export of %d tasks, want the imported and the added task in creation order", len(tasks))
+	}
+
+	if tasks[0].Description != imported.Description || tasks[0].Version != 5 || !slices.Equal(tasks[1].Tags, []string{"ops"}) {
+		return fmt.Errorf("exported description %q version %d tags %v", tasks[0].Description, tasks[0].Version, tasks[1].Tags)
+	}
+
+	imported.Subject = "replaced subject"
+	created, err = storage.ImportTask(aliceCtx, imported)
+	if err != nil || created {
+		return fmt.Errorf("import update created %t with error %v, want updated", created, err)
+	}
+
+	task, err = mustTask(aliceCtx, storage, imported.ID)
+	if err != nil || task.Subject != "replaced subject" || task.Version != 6 {
+		return fmt.Errorf("updated import %q version %d with error %v, want replaced subject version 6", task.Subject, task.Version, err)
+	}
+
+	page, err := storage.Tasks(aliceCtx, entity.TaskQuery{Page: 1, Size: 10, Search: "replaced"})
+	if err != nil || page.Results != 1 {
+		return fmt.Errorf("search of the updated import found %d with error %v, want 1", page.Results, err)
+	}
+
+	_, err = storage.ImportTask(bobCtx, imported)
+	if !errors.Is(err, entity.ErrNotFound) {
+		return fmt.Errorf("other owner import error %v, want %v", err, entity.ErrNotFound)
+	}
+
+	tasks, err = exportTasks(bobCtx, storage)
+	if err != nil || len(tasks) != 0 {
+		return fmt.Errorf("other owner export of %d tasks with error %v, want none", len(tasks), err)
+	}
+
+	return nil
+}
+
+func checkConcurrency(ctx context.Context, storage entity.Storage) error {
+	const workers, tasks = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*tasks)
+	for w := range workers {
+		wg.Go(func() {
+			for t := range tasks {
+				id, err := storage.AddTask(ctx, entity.TaskData{Subject: fmt.Sprintf("worker %d task %d", w, t)})
+				if err != nil {
+					errs <- err
+
+					continue
+				}
+
+				_, _, err = storage.UpdateTaskStatus(ctx, id, entity.TaskStatusInProgress)
+				if err != nil {
+					errs <- err
+				}
+
+				_, err = storage.Tasks(ctx, entity.TaskQuery{Page: 1, Size: 5})
+				if err != nil {
+					errs <- err
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+	close(errs)
+
+	failures := []error{}
+	for err := range errs {
+		failures = append(failures, err)
+	}
+
+	if len(failures) > 0 {
+		return errors.Join(failures...)
+	}
+
+	count, err := storage.TaskCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	if count != workers*tasks {
+		return fmt.Errorf("count %d, want %d", count, workers*tasks)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const TagMaxLength = 32
+
+var (
+	ErrInvalidTag = errors.New("invalid tag")
+	ErrTagExists  = errors.New("tag already exists")
+)
+
+type Tag struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// NormalizeTag returns the lower case tag name, it accepts letters, digits, '-', '_' and '.'.
+func NormalizeTag(name string) (string, error) {
+	tag := strings.ToLower(strings.TrimSpace(name))
+	if tag == "" || utf8.RuneCountInString(tag) > TagMaxLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, name)
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.", r) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidTag, name)
+		}
+	}
+
+	return tag, nil
+}
+
+// NormalizeTags returns the sorted and distinct normalized tag names.
+func NormalizeTags(names []string) ([]string, error) {
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, err := NormalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	slices.Sort(tags)
+
+	return slices.Compact(tags), nil
+}
+
+// ParseTags splits a comma or space separated tag list, e.g. "backend, ops".
+func ParseTags(list string) ([]string, error) {
+	return NormalizeTags(strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}))
+}
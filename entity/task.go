@@ -1,7 +1,11 @@
 package entity
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,29 +17,59 @@ type Task struct {
 	DueDate     time.Time
 	Subject     string
 	Description string
+	Status      TaskStatus
+	StartedAt   *time.Time
+	DoneAt      *time.Time
+	CancelledAt *time.Time
+	Tags        []string
+	Version     int64     // incremented by every update
+	ParentID    uuid.UUID // uuid.Nil of a top-level task
+	Recurrence  Recurrence
+	NextID      uuid.UUID // next occurrence that took the recurrence over, uuid.Nil if not passed on
 }
 
 type TaskData struct {
 	DueDate     time.Time
 	Subject     string
 	Description string
+	Tags        []string
+	Version     int64     // expected version on update, zero skips the check
+	ParentID    uuid.UUID // parent of a new task, an update keeps the parent
+	Recurrence  Recurrence
 }
 
 type TaskOverview struct {
 	CreatedAt time.Time
 	DueDate   time.Time
 	Subject   string
+	Status    TaskStatus
+	Tags      []string
 	ID        uuid.UUID
+	Relevance float64   // search rank, higher matches better
+	Checklist Checklist // of the description
+	Blockers  int       // unfinished tasks that block the task
+	Blocking  int       // unfinished tasks blocked by the task, zero if it's finished
+}
+
+// Subtask is a descendant of a task.
+type Subtask struct {
+	TaskOverview
+
+	ParentID uuid.UUID
+	Depth    int // 1 of the children
 }
 
 type TaskSort int64
 
 type TaskQuery struct {
-	Page   int
-	Size   int
-	Sort   TaskSort
-	Order  SortOrder
-	Filter string
+	Page     int
+	Size     int
+	Sort     TaskSort
+	Order    SortOrder
+	Search   string       // full-text search of subject and description
+	Statuses []TaskStatus // empty matches all
+	Tags     []string     // matches tasks with all tags
+	Blocked  bool         // matches tasks with unfinished blockers only
 }
 
 type TaskPage struct {
@@ -49,14 +83,60 @@ const (
 	TaskSortCreatedAt TaskSort = iota
 	TaskSortDueDate
 	TaskSortSubject
+	TaskSortRelevance   // most relevant search matches first, regardless of the order
 	TaskSortDefault     = TaskSortDueDate
 	TaskPageDefaultSize = 10
 )
 
+// subject length limits in characters
+const (
+	SubjectMinLength = 3
+	SubjectMaxLength = 255
+)
+
+// ErrNotFound is wrapped by storage errors of operations on missing entries.
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidParent is wrapped by storage errors of a missing parent task or a parent that is a subtask of the task.
+var ErrInvalidParent = errors.New("invalid parent task")
+
+// ConflictError is returned by updates of an outdated task version.
+type ConflictError struct {
+	Task Task // current task
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("task %s has changed, current version %d", e.Task.ID, e.Task.Version)
+}
+
+// TransitionError is returned by status updates the current task status can't change to.
+type TransitionError struct {
+	Task   Task // current task
+	Status TaskStatus
+}
+
+func (e TransitionError) Error() string {
+	return fmt.Sprintf("task %s can't change from %s to %s", e.Task.ID, e.Task.Status, e.Status)
+}
+
+// Overview returns the list view of the task.
+func (t Task) Overview() TaskOverview {
+	return TaskOverview{
+		CreatedAt: t.CreatedAt,
+		DueDate:   t.DueDate,
+		Subject:   t.Subject,
+		Status:    t.Status,
+		Tags:      t.Tags,
+		ID:        t.ID,
+		Checklist: ParseChecklist(t.Description),
+	}
+}
+
 var taskSortKeys = []string{
 	"created-at",
 	"due-date",
 	"subject",
+	"relevance",
 }
 
 func (o TaskSort) String() string {
@@ -71,3 +151,41 @@ func TaskSortOrDefault(sort string) TaskSort {
 
 	return TaskSort(o)
 }
+
+// Limit returns the page size, the default size if unset.
+func (q TaskQuery) Limit() int {
+	if q.Size < 1 {
+		return TaskPageDefaultSize
+	}
+
+	return q.Size
+}
+
+// Offset returns the index of the first page entry, the first page if unset.
+func (q TaskQuery) Offset() int {
+	return (max(q.Page, 1) - 1) * q.Limit()
+}
+
+// SortSubtasks orders the subtasks of the root depth-first, the siblings by creation.
+func SortSubtasks(root uuid.UUID, subtasks []Subtask) []Subtask {
+	children := map[uuid.UUID][]Subtask{}
+	for _, s := range subtasks {
+		children[s.ParentID] = append(children[s.ParentID], s)
+	}
+
+	sorted := make([]Subtask, 0, len(subtasks))
+	var walk func(parent uuid.UUID)
+	walk = func(parent uuid.UUID) {
+		siblings := children[parent]
+		slices.SortFunc(siblings, func(a, b Subtask) int {
+			return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID.String(), b.ID.String()))
+		})
+		for _, s := range siblings {
+			sorted = append(sorted, s)
+			walk(s.ID)
+		}
+	}
+	walk(root)
+
+	return sorted
+}
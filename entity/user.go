@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	UserNameMaxLength = 32
+	PasswordMinLength = 8
+	PasswordMaxLength = 72 // bcrypt ignores the bytes after
+)
+
+var (
+	ErrInvalidUserName = errors.New("invalid user name")
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrUserExists      = errors.New("user already exists")
+)
+
+type User struct {
+	ID           uuid.UUID
+	Name         string
+	PasswordHash []byte
+	CreatedAt    time.Time
+}
+
+type userKey struct{}
+
+// unknownUserHash is compared for unknown users to answer in the same time as for wrong passwords.
+var unknownUserHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+
+	return hash
+})
+
+// NormalizeUserName returns the lower case user name, it accepts letters, digits, '-', '_' and '.'.
+func NormalizeUserName(name string) (string, error) {
+	user := strings.ToLower(strings.TrimSpace(name))
+	if user == "" || utf8.RuneCountInString(user) > UserNameMaxLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalidUserName, name)
+	}
+
+	for _, r := range user {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.", r) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidUserName, name)
+		}
+	}
+
+	return user, nil
+}
+
+// HashPassword returns the bcrypt hash of a password with a length between the min and max length.
+func HashPassword(password string) ([]byte, error) {
+	if utf8.RuneCountInString(password) < PasswordMinLength || len(password) > PasswordMaxLength {
+		return nil, fmt.Errorf("%w: requires %d to %d characters", ErrInvalidPassword, PasswordMinLength, PasswordMaxLength)
+	}
+
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// CheckPassword reports whether the password matches, a user without hash never matches.
+func (u User) CheckPassword(password string) bool {
+	if len(u.PasswordHash) == 0 {
+		_ = bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) == nil
+}
+
+// WithUser scopes the task storage operations of the context to the tasks owned by the user.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user of the context, the storage operations without user are unscoped.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+
+	return user, ok
+}
@@ -31,7 +31,12 @@ func WithLocale(ctx context.Context, lang language.Tag) context.Context {
 	return context.WithValue(ctx, LocaleContextKey, newContext(lang))
 }
 
+// LocalizeDate formats the date of the context locale, a zero date is empty, e.g. of a task without due date.
 func LocalizeDate(ctx context.Context, d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+
 	l, ok := ctx.Value(LocaleContextKey).(*localeContext)
 	if !ok {
 		return d.Format(time.DateOnly)
@@ -66,3 +71,13 @@ func TranslateData(ctx context.Context, messageID string, data map[string]string
 
 	return l.translateData(messageID, data)
 }
+
+// Language returns the language of the context locale, undetermined if unset.
+func Language(ctx context.Context) language.Tag {
+	l, ok := ctx.Value(LocaleContextKey).(*localeContext)
+	if !ok {
+		return language.Und
+	}
+
+	return l.lang
+}
@@ -22,39 +22,112 @@ type translator struct {
 }
 
 var messages = [...]*i18n.Message{
+	{ID: "bad_request_body", Other: "Bad Request, invalid body: {{.message}}"},
+	{ID: "bad_request_field", Other: "Bad Request, invalid field '{{.field}}' value '{{.value}}'"},
+	{ID: "bad_request_form_param", Other: "Bad Request, invalid form param '{{.param}}' value '{{.value}}'"},
+	{ID: "bad_request_header", Other: "Bad Request, invalid header '{{.header}}' value '{{.value}}'"},
 	{ID: "bad_request_path_param", Other: "Bad Request, invalid path param '{{.param}}' value '{{.value}}'"},
+	{ID: "bad_request_query_param", Other: "Bad Request, invalid query param '{{.param}}' value '{{.value}}'"},
 	{ID: "client_error", Other: "Client Error"},
+	{ID: "conflict_task_dependency", Other: "Task '{{.blocker}}' can't block task '{{.id}}', it depends on it."},
+	{ID: "conflict_task_status", Other: "The task status can't change from '{{.from}}' to '{{.to}}'."},
 	{ID: "conflict_task_update", Other: "The task update has failed due to an editing conflict. Please try the update again."},
+	{ID: "content_too_large", Other: "Content Too Large, the limit is {{.limit}} bytes"},
 	{ID: "database_error", Other: "Database Error {{.message}}"},
+	{ID: "failed_import_rows", Other: "{{.failed}} of {{.rows}} rows failed."},
+	{ID: "import_row", Other: "row"},
 	{ID: "internal_server_error", Other: "Internal Server Error"},
+	{ID: "invalid_task_field", Other: "Invalid {{.field}} '{{.value}}'."},
+	{ID: "missing_front_matter", Other: "The front matter header between the '---' lines is missing."},
+	{ID: "not_found_checklist_item", Other: "Checklist item '{{.item}}' not found."},
 	{ID: "not_found_path", Other: "Not Found '{{.method}} {{.path}}'"},
 	{ID: "not_found_task", Other: "Task '{{.id}}' not found."},
+	{ID: "not_found_task_dependency", Other: "Task '{{.id}}' isn't blocked by '{{.blocker}}'."},
+	{ID: "not_found_user", Other: "User '{{.name}}' not found."},
 	{ID: "ok_task_created", Other: "Task '{{.id}}' created."},
+	{ID: "ok_task_dependency_added", Other: "Task '{{.id}}' is blocked by '{{.blocker}}'."},
+	{ID: "ok_task_dependency_removed", Other: "Task '{{.id}}' isn't blocked by '{{.blocker}}' anymore."},
+	{ID: "ok_task_recurred", Other: "The next occurrence is task '{{.id}}'."},
+	{ID: "ok_task_status_updated", Other: "Task '{{.id}}' is {{.status}} now."},
+	{ID: "ok_task_unchanged", Other: "Task '{{.id}}' unchanged."},
 	{ID: "ok_task_updated", Other: "Task '{{.id}}' updated."},
+	{ID: "ok_tasks_claimed", Other: "{{.count}} unowned tasks assigned to the user '{{.name}}'."},
+	{ID: "ok_tasks_exported", Other: "{{.count}} tasks exported to {{.path}}."},
+	{ID: "ok_tasks_import_checked", Other: "Dry run of {{.rows}} rows: {{.created}} to create, {{.updated}} to update, {{.failed}} failed."},
+	{ID: "ok_tasks_imported", Other: "{{.rows}} rows imported: {{.created}} created, {{.updated}} updated, {{.failed}} failed."},
+	{ID: "ok_user_created", Other: "User '{{.name}}' created."},
 	{ID: "order_ascending", Other: "ascending"},
 	{ID: "order_descending", Other: "descending"},
 	{ID: "page_number", Other: "page"},
 	{ID: "page_size", Other: "size"},
 	{ID: "page_title", Other: "My Tasks"},
+	{ID: "storage_dsn_missing", Other: "The {{.storage}} storage requires a DSN, see --dsn."},
+	{ID: "storage_scheme_unknown", Other: "Unknown storage scheme '{{.scheme}}', use {{.schemes}}."},
+	{ID: "storage_unavailable", Other: "Connection to the {{.scheme}} storage failed: {{.error}}"},
 	{ID: "task_add", Other: "add"},
+	{ID: "task_add_blocker", Other: "add blocker"},
+	{ID: "task_add_subtask", Other: "add subtask"},
 	{ID: "task_back", Other: "back"},
+	{ID: "task_blocked", Other: "blocked"},
+	{ID: "task_blocked_by", Other: "blocked by"},
+	{ID: "task_blocking", Other: "blocking"},
 	{ID: "task_cancel", Other: "cancel"},
+	{ID: "task_cancelled_at", Other: "cancelled at"},
+	{ID: "task_checklist", Other: "checklist"},
 	{ID: "task_confirm_delete", Other: "Are you sure?"},
+	{ID: "task_conflict_current", Other: "current"},
+	{ID: "task_conflict_yours", Other: "yours"},
 	{ID: "task_count", Other: "count"},
 	{ID: "task_create", Other: "create"},
 	{ID: "task_created_at", Other: "create at"},
 	{ID: "task_creating", Other: "creating"},
+	{ID: "task_dependencies", Other: "dependencies"},
 	{ID: "task_description", Other: "description"},
+	{ID: "task_done_at", Other: "done at"},
 	{ID: "task_due_date", Other: "due date"},
 	{ID: "task_edit", Other: "edit"},
+	{ID: "task_edit_kept", Other: "The changes remain in {{.path}}."},
+	{ID: "task_next_occurrence", Other: "next occurrence"},
 	{ID: "task_order", Other: "order"},
+	{ID: "task_parent", Other: "parent task"},
+	{ID: "task_preview", Other: "preview"},
+	{ID: "task_recurrence", Other: "recurrence"},
+	{ID: "task_recurrence_hint", Other: "RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,FR"},
+	{ID: "task_relevance", Other: "relevance"},
+	{ID: "task_reload", Other: "reload task"},
+	{ID: "task_remove_blocker", Other: "remove blocker"},
 	{ID: "task_results", Other: "results"},
 	{ID: "task_save", Other: "save"},
 	{ID: "task_saving", Other: "saving"},
+	{ID: "task_search", Other: "search"},
+	{ID: "task_search_hint", Other: "subject and description"},
+	{ID: "task_select_blocker", Other: "select a task"},
 	{ID: "task_sort", Other: "sort"},
+	{ID: "task_started_at", Other: "started at"},
+	{ID: "task_status", Other: "status"},
+	{ID: "task_status_all", Other: "all"},
+	{ID: "task_status_cancelled", Other: "cancelled"},
+	{ID: "task_status_done", Other: "done"},
+	{ID: "task_status_in_progress", Other: "in progress"},
+	{ID: "task_status_open", Other: "open"},
+	{ID: "task_status_to_cancelled", Other: "cancel task"},
+	{ID: "task_status_to_done", Other: "complete"},
+	{ID: "task_status_to_in_progress", Other: "start"},
+	{ID: "task_status_to_open", Other: "reopen"},
 	{ID: "task_subject", Other: "subject"},
-	{ID: "task_subject_filter", Other: "subject filter"},
+	{ID: "task_subtasks", Other: "subtasks"},
+	{ID: "task_tags", Other: "tags"},
+	{ID: "task_tags_filter", Other: "tag filter"},
+	{ID: "task_tags_hint", Other: "comma separated, e.g. backend, ops"},
 	{ID: "tasks_loading", Other: "loading"},
+	{ID: "unauthorized", Other: "Unauthorized, please log in."},
+	{ID: "unauthorized_login", Other: "Unknown user name or wrong password."},
+	{ID: "unknown_task_field", Other: "Unknown field '{{.field}}', use {{.fields}}."},
+	{ID: "user_login", Other: "log in"},
+	{ID: "user_logout", Other: "log out"},
+	{ID: "user_name", Other: "user name"},
+	{ID: "user_password", Other: "password"},
+	{ID: "user_password_prompt", Other: "Password"},
 }
 
 func init() {
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type Config struct {
+	Level      string `toml:"level"`       // debug, info, warn or error
+	Format     string `toml:"format"`      // json or text
+	Output     string `toml:"output"`      // stdout, stderr or a file path
+	MaxSize    int    `toml:"max_size"`    // megabytes of the log file before it rotates
+	MaxBackups int    `toml:"max_backups"` // number of kept rotated files, zero keeps all
+	Source     bool   `toml:"source"`      // adds the source code location
+}
+
+// DefaultConfig logs JSON to stdout and keeps three rotated files of 100 MB.
+func DefaultConfig() Config {
+	return Config{Level: "info", Format: "json", Output: "stdout", MaxSize: 100, MaxBackups: 3}
+}
+
+// Validate returns all invalid values.
+func (c Config) Validate() error {
+	var errs []error
+
+	_, err := parseLevel(c.Level)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Format != "" && c.Format != "json" && c.Format != "text" {
+		errs = append(errs, fmt.Errorf("invalid log format %q, use json or text", c.Format))
+	}
+
+	if c.MaxSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid log max size %d, use a positive number of megabytes", c.MaxSize))
+	}
+
+	if c.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("invalid log max backups %d, use zero to keep all", c.MaxBackups))
+	}
+
+	return errors.Join(errs...)
+}
+
+func parseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	if err != nil {
+		return level, fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+
+	return level, nil
+}
@@ -2,22 +2,168 @@
 package log
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+type argsKey struct{}
+
+// contextHandler adds the arguments of the context to the records, see With.
+type contextHandler struct {
+	slog.Handler
+}
+
+// level of the default logger, it is changeable at runtime.
+var level = new(slog.LevelVar)
+
+// output is the configured log file, nil for the standard streams.
+var output io.Closer
+
 func init() {
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+	slog.SetDefault(slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})}))
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.Add(contextArgs(ctx)...)
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Configure replaces the default logger, it closes the previous log file.
+func Configure(config Config) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+	logLevel, _ := parseLevel(config.Level) // validated
+
+	var w io.Writer
+	var file io.Closer
+	switch config.Output {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		rotation := &lumberjack.Logger{
+			Filename:   config.Output,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+		}
+		w, file = rotation, rotation
+	}
+
+	options := &slog.HandlerOptions{AddSource: config.Source, Level: level}
+	var handler slog.Handler = slog.NewJSONHandler(w, options)
+	if config.Format == "text" {
+		handler = slog.NewTextHandler(w, options)
+	}
+
+	err = Close()
+	level.Set(logLevel)
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	output = file
+
+	return err
+}
+
+// Close closes the log file, the standard streams stay open.
+func Close() error {
+	if output == nil {
+		return nil
+	}
+
+	err := output.Close()
+	output = nil
+
+	return err
+}
+
+// Level returns the current level name, e.g. INFO.
+func Level() string {
+	return level.Level().String()
+}
+
+// SetLevel changes the level at runtime, it accepts the slog level names, e.g. debug.
+func SetLevel(name string) error {
+	logLevel, err := parseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(logLevel)
+
+	return nil
+}
+
+// With returns a context with the key value arguments that are added to the records of the context functions.
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, argsKey{}, append(slices.Clip(contextArgs(ctx)), args...))
+}
+
+func contextArgs(ctx context.Context) []any {
+	args, _ := ctx.Value(argsKey{}).([]any)
+
+	return args
+}
+
+func Debug(msg string, args ...any) {
+	write(context.Background(), slog.LevelDebug, msg, args...)
 }
 
 func Error(msg string, err error) {
-	slog.Error(msg, slog.String("error", err.Error()))
+	write(context.Background(), slog.LevelError, msg, slog.String("error", err.Error()))
 }
 
 func Info(msg string, args ...any) {
-	slog.Info(msg, args...)
+	write(context.Background(), slog.LevelInfo, msg, args...)
 }
 
 func Warn(msg string, args ...any) {
-	slog.Warn(msg, args...)
+	write(context.Background(), slog.LevelWarn, msg, args...)
+}
+
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	write(ctx, slog.LevelDebug, msg, args...)
+}
+
+func ErrorContext(ctx context.Context, msg string, err error) {
+	write(ctx, slog.LevelError, msg, slog.String("error", err.Error()))
+}
+
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	write(ctx, slog.LevelInfo, msg, args...)
+}
+
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	write(ctx, slog.LevelWarn, msg, args...)
+}
+
+// write logs the record with the source location of the caller of the exported function.
+func write(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logger := slog.Default()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skips Callers, write and the exported function
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+
+	_ = logger.Handler().Handle(ctx, record)
 }
@@ -0,0 +1,155 @@
+// Package markdown renders the GitHub flavored Markdown of the task descriptions and toggles their checklist items.
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// toggleAttr is the htmx attribute of a clickable checkbox, data prefixed to pass the sanitizer.
+const toggleAttr = "data-hx-put"
+
+// converter parses the tables, strikethroughs, autolinks and task lists, the policy sanitizes the HTML of the shared descriptions.
+var (
+	converter = goldmark.New(
+		goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify, extension.TaskList),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(checkBoxRenderer{}, 100))),
+	)
+	policy = newPolicy()
+)
+
+// newPolicy allows the user generated content elements and the checkboxes of task lists.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs(toggleAttr).Matching(regexp.MustCompile(`^/tasks/[0-9a-f-]{36}/checklist/[0-9]+$`)).OnElements("input")
+
+	return p
+}
+
+// Render writes the sanitized HTML of the source, the checklist items are disabled without toggle URL.
+// With URL a checkbox sends a PUT request to the URL with the item number appended, e.g. /tasks/{id}/checklist/0.
+func Render(w io.Writer, source, toggleURL string) error {
+	src := []byte(source)
+	doc := converter.Parser().Parse(text.NewReader(src))
+
+	if toggleURL != "" {
+		item := 0
+		walkCheckBoxes(doc, func(box *extast.TaskCheckBox) {
+			box.SetAttributeString(toggleAttr, toggleURL+"/"+strconv.Itoa(item))
+			item++
+		})
+	}
+
+	var buf bytes.Buffer
+	err := converter.Renderer().Render(&buf, src, doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(policy.SanitizeBytes(buf.Bytes()))
+
+	return err
+}
+
+// Checklist returns the number of checked and of all task list items, e.g. "- [x] done" and "- [ ] open".
+func Checklist(source string) (int, int) {
+	done, total := 0, 0
+	walkCheckBoxes(converter.Parser().Parse(text.NewReader([]byte(source))), func(box *extast.TaskCheckBox) {
+		if box.IsChecked {
+			done++
+		}
+		total++
+	})
+
+	return done, total
+}
+
+// ToggleChecklistItem checks or unchecks the task list item of the number (zero based), false if it doesn't exist.
+func ToggleChecklistItem(source string, item int) (string, bool) {
+	src := []byte(source)
+	pos := -1
+	i := 0
+	walkCheckBoxes(converter.Parser().Parse(text.NewReader(src)), func(box *extast.TaskCheckBox) {
+		if i == item {
+			pos = checkBoxPos(src, box)
+		}
+		i++
+	})
+
+	if pos == -1 {
+		return source, false
+	}
+
+	if src[pos] == ' ' {
+		src[pos] = 'x'
+	} else {
+		src[pos] = ' '
+	}
+
+	return string(src), true
+}
+
+func walkCheckBoxes(doc ast.Node, visit func(*extast.TaskCheckBox)) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if box, ok := n.(*extast.TaskCheckBox); ok && entering {
+			visit(box)
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// checkBoxPos returns the source position of the check mark, the box starts the first line of its paragraph.
+func checkBoxPos(src []byte, box *extast.TaskCheckBox) int {
+	lines := box.Parent().Lines()
+	if lines.Len() == 0 {
+		return -1
+	}
+
+	start := lines.At(0).Start
+	if start+2 >= len(src) || src[start] != '[' || src[start+2] != ']' {
+		return -1
+	}
+
+	return start + 1
+}
+
+// checkBoxRenderer renders the task list checkboxes with their attributes, disabled without toggle URL.
+type checkBoxRenderer struct{}
+
+func (r checkBoxRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(extast.KindTaskCheckBox, r.render)
+}
+
+func (r checkBoxRenderer) render(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	box, _ := node.(*extast.TaskCheckBox)
+
+	_, _ = w.WriteString(`<input type="checkbox"`)
+	if box.IsChecked {
+		_, _ = w.WriteString(` checked=""`)
+	}
+	if _, ok := box.AttributeString(toggleAttr); !ok {
+		_, _ = w.WriteString(` disabled=""`)
+	}
+	html.RenderAttributes(w, box, nil)
+	_, _ = w.WriteString("> ")
+
+	return ast.WalkContinue, nil
+}
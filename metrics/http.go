@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HTTP records the requests by route pattern.
+type HTTP struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewHTTP(registerer prometheus.Registerer) *HTTP {
+	h := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled requests by route pattern and status code.",
+		}, []string{"pattern", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the handled requests by route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"pattern"}),
+	}
+	registerer.MustRegister(h.requests, h.duration)
+
+	return h
+}
+
+// Handler instruments the handler of the route pattern.
+func (h *HTTP) Handler(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	labels := prometheus.Labels{"pattern": pattern}
+
+	return promhttp.InstrumentHandlerDuration(h.duration.MustCurryWith(labels),
+		promhttp.InstrumentHandlerCounter(h.requests.MustCurryWith(labels), next))
+}
@@ -0,0 +1,25 @@
+// Package metrics provides the Prometheus instrumentation of the web server and the storage.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "tasks"
+
+// CollectorSource is implemented by storages with own metrics, e.g. the connection pool stats.
+type CollectorSource interface {
+	MetricsCollector() prometheus.Collector
+}
+
+// NewRegistry returns a registry with the Go runtime and process metrics.
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return registry
+}
@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// taskCountTimeout limits the task count query of a scrape.
+const taskCountTimeout = 5 * time.Second
+
+// Storage records the duration and the errors of the storage calls by method.
+type Storage struct {
+	entity.Storage
+
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func NewStorage(storage entity.Storage, registerer prometheus.Registerer) *Storage {
+	s := &Storage{
+		Storage: storage,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "call_duration_seconds",
+			Help:      "Duration of the storage calls by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "call_errors_total",
+			Help:      "Number of failed storage calls by method.",
+		}, []string{"method"}),
+	}
+
+	registerer.MustRegister(s.duration, s.errors, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stored",
+		Help:      "Number of stored tasks of all users.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), taskCountTimeout)
+		defer cancel()
+
+		count, err := storage.TaskCount(ctx)
+		if err != nil {
+			log.Error("task count metric access failed", err)
+		}
+
+		return float64(count)
+	}))
+
+	return s
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.Storage.Ping(ctx)
+	s.observe("Ping", start, err)
+
+	return err
+}
+
+func (s *Storage) AddTask(ctx context.Context, data entity.TaskData) (uuid.UUID, error) {
+	start := time.Now()
+	id, err := s.Storage.AddTask(ctx, data)
+	s.observe("AddTask", start, err)
+
+	return id, err
+}
+
+func (s *Storage) TaskCount(ctx context.Context) (int, error) {
+	start := time.Now()
+	count, err := s.Storage.TaskCount(ctx)
+	s.observe("TaskCount", start, err)
+
+	return count, err
+}
+
+func (s *Storage) Task(ctx context.Context, id uuid.UUID) (entity.Task, bool, error) {
+	start := time.Now()
+	task, found, err := s.Storage.Task(ctx, id)
+	s.observe("Task", start, err)
+
+	return task, found, err
+}
+
+func (s *Storage) Tasks(ctx context.Context, query entity.TaskQuery) (entity.TaskPage, error) {
+	start := time.Now()
+	page, err := s.Storage.Tasks(ctx, query)
+	s.observe("Tasks", start, err)
+
+	return page, err
+}
+
+func (s *Storage) DeleteTask(ctx context.Context, id uuid.UUID) error {
+	start := time.Now()
+	err := s.Storage.DeleteTask(ctx, id)
+	s.observe("DeleteTask", start, err)
+
+	return err
+}
+
+func (s *Storage) UpdateTask(ctx context.Context, id uuid.UUID, data entity.TaskData) (entity.Task, bool, error) {
+	start := time.Now()
+	task, found, err := s.Storage.UpdateTask(ctx, id, data)
+	s.observe("UpdateTask", start, err)
+
+	return task, found, err
+}
+
+func (s *Storage) UpdateTaskStatus(ctx context.Context, id uuid.UUID, status entity.TaskStatus) (entity.Task, bool, error) {
+	start := time.Now()
+	task, found, err := s.Storage.UpdateTaskStatus(ctx, id, status)
+	s.observe("UpdateTaskStatus", start, err)
+
+	return task, found, err
+}
+
+// ExportTasks observes the duration of the whole export.
+func (s *Storage) ExportTasks(ctx context.Context) iter.Seq2[entity.Task, error] {
+	return func(yield func(entity.Task, error) bool) {
+		start := time.Now()
+		var err error
+		for task, taskErr := range s.Storage.ExportTasks(ctx) {
+			err = taskErr
+			if !yield(task, taskErr) {
+				break
+			}
+		}
+		s.observe("ExportTasks", start, err)
+	}
+}
+
+func (s *Storage) ImportTask(ctx context.Context, task entity.Task) (bool, error) {
+	start := time.Now()
+	created, err := s.Storage.ImportTask(ctx, task)
+	s.observe("ImportTask", start, err)
+
+	return created, err
+}
+
+func (s *Storage) Subtasks(ctx context.Context, id uuid.UUID) ([]entity.Subtask, error) {
+	start := time.Now()
+	subtasks, err := s.Storage.Subtasks(ctx, id)
+	s.observe("Subtasks", start, err)
+
+	return subtasks, err
+}
+
+func (s *Storage) Ancestors(ctx context.Context, id uuid.UUID) ([]entity.TaskOverview, error) {
+	start := time.Now()
+	ancestors, err := s.Storage.Ancestors(ctx, id)
+	s.observe("Ancestors", start, err)
+
+	return ancestors, err
+}
+
+func (s *Storage) DeleteTaskTree(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	start := time.Now()
+	ids, err := s.Storage.DeleteTaskTree(ctx, id)
+	s.observe("DeleteTaskTree", start, err)
+
+	return ids, err
+}
+
+func (s *Storage) AddDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	start := time.Now()
+	err := s.Storage.AddDependency(ctx, id, blockerID)
+	s.observe("AddDependency", start, err)
+
+	return err
+}
+
+func (s *Storage) RemoveDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	start := time.Now()
+	err := s.Storage.RemoveDependency(ctx, id, blockerID)
+	s.observe("RemoveDependency", start, err)
+
+	return err
+}
+
+func (s *Storage) Dependencies(ctx context.Context, id uuid.UUID) (entity.TaskDependencies, error) {
+	start := time.Now()
+	dependencies, err := s.Storage.Dependencies(ctx, id)
+	s.observe("Dependencies", start, err)
+
+	return dependencies, err
+}
+
+func (s *Storage) WorkPlan(ctx context.Context) ([]entity.TaskOverview, error) {
+	start := time.Now()
+	plan, err := s.Storage.WorkPlan(ctx)
+	s.observe("WorkPlan", start, err)
+
+	return plan, err
+}
+
+func (s *Storage) RecurTasks(ctx context.Context, now time.Time) ([]entity.Occurrence, error) {
+	start := time.Now()
+	occurrences, err := s.Storage.RecurTasks(ctx, now)
+	s.observe("RecurTasks", start, err)
+
+	return occurrences, err
+}
+
+func (s *Storage) RecurTask(ctx context.Context, id uuid.UUID, now time.Time) (entity.Occurrence, bool, error) {
+	start := time.Now()
+	occurrence, recurred, err := s.Storage.RecurTask(ctx, id, now)
+	s.observe("RecurTask", start, err)
+
+	return occurrence, recurred, err
+}
+
+func (s *Storage) AddTag(ctx context.Context, name string) (entity.Tag, error) {
+	start := time.Now()
+	tag, err := s.Storage.AddTag(ctx, name)
+	s.observe("AddTag", start, err)
+
+	return tag, err
+}
+
+func (s *Storage) Tags(ctx context.Context) ([]entity.Tag, error) {
+	start := time.Now()
+	tags, err := s.Storage.Tags(ctx)
+	s.observe("Tags", start, err)
+
+	return tags, err
+}
+
+func (s *Storage) RenameTag(ctx context.Context, id uuid.UUID, name string) (entity.Tag, bool, error) {
+	start := time.Now()
+	tag, found, err := s.Storage.RenameTag(ctx, id, name)
+	s.observe("RenameTag", start, err)
+
+	return tag, found, err
+}
+
+func (s *Storage) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	start := time.Now()
+	err := s.Storage.DeleteTag(ctx, id)
+	s.observe("DeleteTag", start, err)
+
+	return err
+}
+
+func (s *Storage) AddUser(ctx context.Context, name, password string) (entity.User, error) {
+	start := time.Now()
+	user, err := s.Storage.AddUser(ctx, name, password)
+	s.observe("AddUser", start, err)
+
+	return user, err
+}
+
+func (s *Storage) User(ctx context.Context, id uuid.UUID) (entity.User, bool, error) {
+	start := time.Now()
+	user, found, err := s.Storage.User(ctx, id)
+	s.observe("User", start, err)
+
+	return user, found, err
+}
+
+func (s *Storage) UserByName(ctx context.Context, name string) (entity.User, bool, error) {
+	start := time.Now()
+	user, found, err := s.Storage.UserByName(ctx, name)
+	s.observe("UserByName", start, err)
+
+	return user, found, err
+}
+
+func (s *Storage) ClaimTasks(ctx context.Context, userID uuid.UUID) (int, error) {
+	start := time.Now()
+	claimed, err := s.Storage.ClaimTasks(ctx, userID)
+	s.observe("ClaimTasks", start, err)
+
+	return claimed, err
+}
+
+func (s *Storage) observe(method string, start time.Time, err error) {
+	s.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.errors.WithLabelValues(method).Inc()
+	}
+}
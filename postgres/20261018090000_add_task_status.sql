@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+    ADD COLUMN status smallint NOT NULL DEFAULT 0,
+    ADD COLUMN started_at timestamp,
+    ADD COLUMN done_at timestamp,
+    ADD COLUMN cancelled_at timestamp;
+-- +goose StatementEnd
+
+CREATE INDEX task_status_idx ON task (status);
+
+-- +goose Down
+DROP INDEX task_status_idx;
+
+-- +goose StatementBegin
+ALTER TABLE task
+    DROP COLUMN cancelled_at,
+    DROP COLUMN done_at,
+    DROP COLUMN started_at,
+    DROP COLUMN status;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+    ADD COLUMN search_simple tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(subject, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED,
+    ADD COLUMN search_english tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(subject, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED,
+    ADD COLUMN search_german tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('german', coalesce(subject, '')), 'A') ||
+        setweight(to_tsvector('german', coalesce(description, '')), 'B')
+    ) STORED;
+-- +goose StatementEnd
+
+CREATE INDEX task_search_simple_idx ON task USING GIN (search_simple);
+CREATE INDEX task_search_english_idx ON task USING GIN (search_english);
+CREATE INDEX task_search_german_idx ON task USING GIN (search_german);
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE task
+    DROP COLUMN search_german,
+    DROP COLUMN search_english,
+    DROP COLUMN search_simple;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('task_events', json_build_object('type', 'deleted', 'id', OLD.id)::text);
+
+        RETURN OLD;
+    END IF;
+
+    PERFORM pg_notify('task_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END,
+        'id', NEW.id)::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER task_events AFTER INSERT OR UPDATE OR DELETE ON task
+    FOR EACH ROW EXECUTE FUNCTION notify_task_event();
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER task_events ON task;
+DROP FUNCTION notify_task_event();
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE users (
+    id uuid NOT NULL,
+    name varchar(32) NOT NULL,
+    password_hash bytea NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id),
+    UNIQUE (name)
+);
+-- +goose StatementEnd
+
+ALTER TABLE task ADD COLUMN owner_id uuid REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX task_owner_idx ON task (owner_id);
+
+-- +goose Down
+ALTER TABLE task DROP COLUMN owner_id;
+DROP TABLE users;
@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE task_dependency (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    blocker_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, blocker_id),
+    CHECK (task_id <> blocker_id)
+);
+
+CREATE INDEX task_dependency_blocker_idx ON task_dependency (blocker_id);
+
+-- +goose StatementBegin
+CREATE FUNCTION notify_task_dependency_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', OLD.task_id)::text);
+        PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', OLD.blocker_id)::text);
+
+        RETURN OLD;
+    END IF;
+
+    PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', NEW.task_id)::text);
+    PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', NEW.blocker_id)::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER task_dependency_events AFTER INSERT OR DELETE ON task_dependency
+    FOR EACH ROW EXECUTE FUNCTION notify_task_dependency_event();
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE task_dependency;
+DROP FUNCTION notify_task_dependency_event();
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE task ADD COLUMN recurrence text NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE task DROP COLUMN recurrence;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_tag_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('task_events', json_build_object('type', 'changed')::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- the tag upsert of a task update keeps the name
+CREATE TRIGGER tag_renamed_events AFTER UPDATE ON tag
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION notify_tag_event();
+
+CREATE TRIGGER tag_deleted_events AFTER DELETE ON tag
+    FOR EACH ROW EXECUTE FUNCTION notify_tag_event();
+
+-- +goose Down
+DROP TRIGGER tag_deleted_events ON tag;
+DROP TRIGGER tag_renamed_events ON tag;
+DROP FUNCTION notify_tag_event();
@@ -0,0 +1,86 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('task_events', json_build_object('type', 'deleted', 'id', OLD.id, 'owner', OLD.owner_id)::text);
+
+        RETURN OLD;
+    END IF;
+
+    PERFORM pg_notify('task_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END,
+        'id', NEW.id,
+        'owner', NEW.owner_id)::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- the owner of a task deleted by a cascade is unknown, its delete event follows
+-- +goose StatementBegin
+CREATE FUNCTION notify_task_updated(updated_id uuid) RETURNS void AS $$
+BEGIN
+    PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', updated_id,
+        'owner', (SELECT owner_id FROM task WHERE id = updated_id))::text);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_task_dependency_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM notify_task_updated(OLD.task_id);
+        PERFORM notify_task_updated(OLD.blocker_id);
+
+        RETURN OLD;
+    END IF;
+
+    PERFORM notify_task_updated(NEW.task_id);
+    PERFORM notify_task_updated(NEW.blocker_id);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_task_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('task_events', json_build_object('type', 'deleted', 'id', OLD.id)::text);
+
+        RETURN OLD;
+    END IF;
+
+    PERFORM pg_notify('task_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END,
+        'id', NEW.id)::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_task_dependency_event() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', OLD.task_id)::text);
+        PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', OLD.blocker_id)::text);
+
+        RETURN OLD;
+    END IF;
+
+    PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', NEW.task_id)::text);
+    PERFORM pg_notify('task_events', json_build_object('type', 'updated', 'id', NEW.blocker_id)::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP FUNCTION notify_task_updated(uuid);
@@ -0,0 +1,6 @@
+-- +goose Up
+-- the next occurrence of a task that passed its recurrence on, kept without reference after its deletion
+ALTER TABLE task ADD COLUMN next_id uuid;
+
+-- +goose Down
+ALTER TABLE task DROP COLUMN next_id;
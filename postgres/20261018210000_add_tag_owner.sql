@@ -0,0 +1,63 @@
+-- +goose Up
+ALTER TABLE tag ADD COLUMN owner_id uuid REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE tag DROP CONSTRAINT tag_name_key;
+
+-- the tags are split by the owners of their tasks, the first owner keeps the tag ID
+-- +goose StatementBegin
+CREATE TEMPORARY TABLE tag_split AS
+SELECT tag_id, owner_id, CASE WHEN row_number() OVER (PARTITION BY tag_id ORDER BY owner_id NULLS FIRST) = 1 THEN tag_id
+    ELSE gen_random_uuid() END AS id
+FROM (SELECT DISTINCT task_tag.tag_id, task.owner_id FROM task_tag JOIN task ON task.id = task_tag.task_id) AS used;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO tag (id, name, owner_id)
+SELECT tag_split.id, tag.name, tag_split.owner_id FROM tag_split JOIN tag ON tag.id = tag_split.tag_id
+WHERE tag_split.id <> tag_split.tag_id;
+-- +goose StatementEnd
+
+UPDATE tag SET owner_id = tag_split.owner_id FROM tag_split WHERE tag_split.id = tag.id;
+
+-- +goose StatementBegin
+UPDATE task_tag SET tag_id = tag_split.id FROM task, tag_split
+WHERE task.id = task_tag.task_id AND tag_split.tag_id = task_tag.tag_id
+    AND tag_split.owner_id IS NOT DISTINCT FROM task.owner_id AND tag_split.id <> tag_split.tag_id;
+-- +goose StatementEnd
+
+DROP TABLE tag_split;
+
+CREATE UNIQUE INDEX tag_owner_name_idx ON tag (coalesce(owner_id, '00000000-0000-0000-0000-000000000000'), name);
+
+-- a renamed or deleted tag changes the tasks of its owner
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_tag_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('task_events', json_build_object('type', 'changed', 'owner', OLD.owner_id)::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_tag_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('task_events', json_build_object('type', 'changed')::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- the tags of all owners are merged by name
+-- +goose StatementBegin
+UPDATE task_tag SET tag_id = kept.id FROM tag, (SELECT DISTINCT ON (name) id, name FROM tag ORDER BY name, id) AS kept
+WHERE tag.id = task_tag.tag_id AND kept.name = tag.name AND kept.id <> tag.id;
+-- +goose StatementEnd
+
+DELETE FROM tag WHERE id NOT IN (SELECT DISTINCT ON (name) id FROM tag ORDER BY name, id);
+
+DROP INDEX tag_owner_name_idx;
+ALTER TABLE tag DROP COLUMN owner_id;
+ALTER TABLE tag ADD CONSTRAINT tag_name_key UNIQUE (name);
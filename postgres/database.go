@@ -3,30 +3,99 @@ package postgres
 
 import (
 	"context"
+	"embed"
 	"errors"
 	"fmt"
+	"iter"
 	"strings"
+	"time"
 
 	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/locale"
 	"github.com/dgf/go-ssr-x/log"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"golang.org/x/text/language"
 )
 
+//go:embed *.sql
+var migrations embed.FS
+
+// tagsColumn selects the sorted tag names of a task.
+const tagsColumn = `array(SELECT tag.name FROM tag
+	JOIN task_tag ON tag.id = task_tag.tag_id WHERE task_tag.task_id = task.id ORDER BY tag.name) AS tags`
+
+// dependencyColumns count the unfinished (open and in progress) blockers of a task and the unfinished tasks it blocks.
+const dependencyColumns = `(SELECT count(*) FROM task_dependency JOIN task AS blocker ON blocker.id = task_dependency.blocker_id
+		WHERE task_dependency.task_id = task.id AND blocker.status IN (0, 1)) AS blockers,
+	(SELECT count(*) FROM task_dependency JOIN task AS blocked ON blocked.id = task_dependency.task_id
+		WHERE task_dependency.blocker_id = task.id AND blocked.status IN (0, 1) AND task.status IN (0, 1)) AS blocking`
+
+// taskOverviewRow is a listed task with the description of its checklist.
+type taskOverviewRow struct {
+	entity.TaskOverview
+
+	Description string
+}
+
 type Database struct {
 	db *pgxpool.Pool
 }
 
+func init() {
+	open := func(ctx context.Context, dsn string) (entity.Storage, error) {
+		database, err := NewDatabase(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		return database, nil
+	}
+	entity.Register("postgres", open)
+	entity.Register("postgresql", open)
+}
+
 func NewDatabase(ctx context.Context, connStr string) (*Database, error) {
 	dbpool, err := pgxpool.New(ctx, connStr)
 	if err != nil {
 		return nil, err
 	}
 
+	err = migrate(ctx, dbpool)
+	if err != nil {
+		dbpool.Close()
+
+		return nil, fmt.Errorf("database migration failed: %w", err)
+	}
+
 	return &Database{db: dbpool}, nil
 }
 
+// migrate runs the embedded migrations on a standard library wrapper of the pool.
+func migrate(ctx context.Context, dbpool *pgxpool.Pool) error {
+	db := stdlib.OpenDBFromPool(dbpool)
+	defer db.Close()
+
+	migration, err := goose.NewProvider(goose.DialectPostgres, db, migrations)
+	if err != nil {
+		return err
+	}
+
+	results, err := migration.Up(ctx)
+	for _, result := range results {
+		log.Info("database migration applied", "version", result.Source.Version, "path", result.Source.Path)
+	}
+
+	return err
+}
+
+func (d *Database) Ping(ctx context.Context) error {
+	return d.db.Ping(ctx)
+}
+
 func (d *Database) Close() error {
 	d.db.Close()
 
@@ -34,36 +103,78 @@ func (d *Database) Close() error {
 }
 
 func (d *Database) AddTask(ctx context.Context, data entity.TaskData) (uuid.UUID, error) {
-	const sql = "INSERT INTO task (id, due_date, subject, description) VALUES ($1, $2, $3, $4)"
+	const sql = "INSERT INTO task (id, due_date, subject, description, owner_id, parent_id, recurrence) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	tags, err := entity.NormalizeTags(data.Tags)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer rollback(ctx, tx)
 
 	id := uuid.New()
-	_, err := d.db.Exec(ctx, sql, id, data.DueDate, data.Subject, data.Description)
+	err = checkParent(ctx, tx, id, data.ParentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(ctx, sql, id, data.DueDate, data.Subject, data.Description, ownerArg(ctx), parentArg(data.ParentID), data.Recurrence)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	return id, nil
+	err = setTaskTags(ctx, tx, id, tags)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, tx.Commit(ctx)
 }
 
 func (d *Database) DeleteTask(ctx context.Context, id uuid.UUID) error {
-	const sql = "DELETE FROM task WHERE id = $1"
+	const (
+		parentSQL   = "SELECT parent_id FROM task WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2) FOR UPDATE"
+		reparentSQL = "UPDATE task SET parent_id = $2 WHERE parent_id = $1"
+		deleteSQL   = "DELETE FROM task WHERE id = $1"
+	)
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback(ctx, tx)
 
-	tag, err := d.db.Exec(ctx, sql, id)
+	var parent uuid.NullUUID
+	err = tx.QueryRow(ctx, parentSQL, id, ownerArg(ctx)).Scan(&parent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("no row for %s: %w", id, entity.ErrNotFound)
+	}
 	if err != nil {
 		return err
 	}
 
-	if tag.RowsAffected() != 1 {
-		return fmt.Errorf("no row for %s", id)
+	_, err = tx.Exec(ctx, reparentSQL, id, parent)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	_, err = tx.Exec(ctx, deleteSQL, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (d *Database) Task(ctx context.Context, id uuid.UUID) (entity.Task, bool, error) {
-	const sql = "SELECT id, created_at, due_date, subject, description FROM task WHERE id = $1"
+	const sql = `SELECT id, created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, parent_id, recurrence, next_id, ` +
+		tagsColumn + ` FROM task WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2)`
 
-	rows, err := d.db.Query(ctx, sql, id)
+	rows, err := d.db.Query(ctx, sql, id, ownerArg(ctx))
 	if err != nil {
 		return entity.Task{}, false, err
 	}
@@ -81,10 +192,10 @@ func (d *Database) Task(ctx context.Context, id uuid.UUID) (entity.Task, bool, e
 }
 
 func (d *Database) TaskCount(ctx context.Context) (int, error) {
-	const sql = "SELECT count(*) FROM task"
+	const sql = "SELECT count(*) FROM task WHERE ($1::uuid IS NULL OR owner_id = $1)"
 
 	var count int
-	err := d.db.QueryRow(ctx, sql).Scan(&count)
+	err := d.db.QueryRow(ctx, sql, ownerArg(ctx)).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -93,74 +204,331 @@ func (d *Database) TaskCount(ctx context.Context) (int, error) {
 }
 
 func (d *Database) Tasks(ctx context.Context, query entity.TaskQuery) (entity.TaskPage, error) {
-	page := entity.TaskPage{Start: (query.Page - 1) * query.Size}
+	const countSQL = "SELECT count(*) FROM task WHERE ($1::uuid IS NULL OR owner_id = $1)"
 
-	const resultsQuery = "SELECT count(*) FROM task WHERE subject LIKE $1"
-	const rowSelectQuery = "SELECT id, created_at, due_date, subject FROM task WHERE subject LIKE $1"
-	rowsQuery := fmt.Sprintf("%s ORDER BY %s LIMIT %d OFFSET %d", rowSelectQuery,
-		taskOrderClause(query.Sort, query.Order), query.Size, (query.Page-1)*query.Size)
-	subjectLike := likeArg(query.Filter)
+	page := entity.TaskPage{Start: query.Offset()}
 
-	tx, err := d.db.Begin(ctx)
+	where, relevance, args := taskFilter(ctx, query)
+	resultsQuery := "SELECT count(*) FROM task " + where
+	rowsQuery := fmt.Sprintf("SELECT id, created_at, due_date, subject, status, %s AS relevance, %s, description, %s FROM task %s ORDER BY %s LIMIT %d OFFSET %d",
+		relevance, tagsColumn, dependencyColumns, where, taskOrderClause(query.Sort, query.Order), query.Limit(), query.Offset())
+
+	tx, err := d.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
 	if err != nil {
 		return entity.TaskPage{}, err
 	}
+	defer rollback(ctx, tx)
 
-	defer func() {
-		err := tx.Rollback(ctx)
-		if err != nil {
-			log.Error("task list query read rollback failed", err)
-		}
-	}()
+	err = tx.QueryRow(ctx, countSQL, ownerArg(ctx)).Scan(&page.Count)
+	if err != nil {
+		return page, err
+	}
 
-	count, err := d.TaskCount(ctx)
+	err = tx.QueryRow(ctx, resultsQuery, args...).Scan(&page.Results)
 	if err != nil {
 		return page, err
 	}
-	page.Count = count
 
-	if page.Start > count {
+	if page.Start >= page.Results {
+		page.Tasks = []entity.TaskOverview{}
+
 		return page, nil
 	}
 
-	var results int
-	err = d.db.QueryRow(ctx, resultsQuery, subjectLike).Scan(&results)
+	rows, err := tx.Query(ctx, rowsQuery, args...)
 	if err != nil {
 		return page, err
 	}
-	page.Results = results
 
-	rows, err := d.db.Query(ctx, rowsQuery, subjectLike)
+	tasks, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[taskOverviewRow])
 	if err != nil {
 		return page, err
 	}
 
-	tasks, err := pgx.CollectRows(rows, pgx.RowToStructByName[entity.TaskOverview])
-	if err != nil {
-		return page, err
+	page.Tasks = make([]entity.TaskOverview, len(tasks))
+	for t, task := range tasks {
+		task.Checklist = entity.ParseChecklist(task.Description)
+		page.Tasks[t] = task.TaskOverview
 	}
-	page.Tasks = tasks
 
 	return page, nil
 }
 
 func (d *Database) UpdateTask(ctx context.Context, id uuid.UUID, data entity.TaskData) (entity.Task, bool, error) {
-	const sql = "UPDATE task SET (due_date, subject, description) = ($2, $3, $4) WHERE id = $1"
+	const sql = `UPDATE task SET (due_date, subject, description, recurrence, version) = ($2, $3, $4, $5, version + 1)
+		WHERE id = $1 AND $6 IN (0, version) AND ($7::uuid IS NULL OR owner_id = $7)`
 
-	tag, err := d.db.Exec(ctx, sql, id, data.DueDate, data.Subject, data.Description)
+	tags, err := entity.NormalizeTags(data.Tags)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+	defer rollback(ctx, tx)
+
+	tag, err := tx.Exec(ctx, sql, id, data.DueDate, data.Subject, data.Description, data.Recurrence, data.Version, ownerArg(ctx))
 	if err != nil {
 		return entity.Task{}, false, err
 	}
 
 	if tag.RowsAffected() != 1 {
-		return entity.Task{}, false, fmt.Errorf("no row for %s", id)
+		rollback(ctx, tx)
+
+		return d.conflict(ctx, id)
+	}
+
+	err = setTaskTags(ctx, tx, id, tags)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return entity.Task{}, false, err
 	}
 
 	return d.Task(ctx, id)
 }
 
-func likeArg(arg string) string {
-	return "%" + strings.ReplaceAll(arg, "%", "") + "%"
+// conflict returns the current task as conflict of a failed update, not found if it was deleted.
+func (d *Database) conflict(ctx context.Context, id uuid.UUID) (entity.Task, bool, error) {
+	task, ok, err := d.Task(ctx, id)
+	if err != nil || !ok {
+		return entity.Task{}, false, err
+	}
+
+	return entity.Task{}, true, entity.ConflictError{Task: task}
+}
+
+func (d *Database) UpdateTaskStatus(ctx context.Context, id uuid.UUID, status entity.TaskStatus) (entity.Task, bool, error) {
+	sql, args := statusUpdate(id, ownerArg(ctx), status, time.Now())
+
+	tag, err := d.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	task, found, err := d.Task(ctx, id)
+	if err == nil && found && tag.RowsAffected() == 0 {
+		return entity.Task{}, true, entity.TransitionError{Task: task, Status: status}
+	}
+
+	return task, found, err
+}
+
+func (d *Database) ExportTasks(ctx context.Context) iter.Seq2[entity.Task, error] {
+	const sql = `WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0 FROM task WHERE parent_id IS NULL
+			UNION ALL
+			SELECT task.id, tree.depth + 1 FROM task JOIN tree ON task.parent_id = tree.id
+		)
+		SELECT task.id, created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, parent_id, recurrence, next_id, ` +
+		tagsColumn + ` FROM task JOIN tree ON task.id = tree.id WHERE ($1::uuid IS NULL OR owner_id = $1) ORDER BY tree.depth, created_at, task.id`
+
+	return func(yield func(entity.Task, error) bool) {
+		rows, err := d.db.Query(ctx, sql, ownerArg(ctx))
+		if err != nil {
+			yield(entity.Task{}, err)
+
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			task, err := pgx.RowToStructByName[entity.Task](rows)
+			if err != nil {
+				yield(task, err)
+
+				return
+			}
+
+			if !yield(task, nil) {
+				return
+			}
+		}
+
+		err = rows.Err()
+		if err != nil {
+			yield(entity.Task{}, err)
+		}
+	}
+}
+
+func (d *Database) ImportTask(ctx context.Context, task entity.Task) (bool, error) {
+	const (
+		ownerSQL  = "SELECT owner_id FROM task WHERE id = $1 FOR UPDATE"
+		insertSQL = `INSERT INTO task (id, created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, owner_id, parent_id, recurrence, next_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+		updateSQL = `UPDATE task SET (created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, parent_id, recurrence, next_id)
+			= ($2, $3, $4, $5, $6, $7, $8, $9, version + 1, $10, $11, $12) WHERE id = $1`
+	)
+
+	tags, err := entity.NormalizeTags(task.Tags)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer rollback(ctx, tx)
+
+	var owner uuid.NullUUID
+	err = tx.QueryRow(ctx, ownerSQL, task.ID).Scan(&owner)
+	created := errors.Is(err, pgx.ErrNoRows)
+	if err != nil && !created {
+		return false, err
+	}
+
+	if !created && !owns(ctx, owner) {
+		return false, fmt.Errorf("task %s of another user: %w", task.ID, entity.ErrNotFound)
+	}
+
+	err = checkParent(ctx, tx, task.ID, task.ParentID)
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		_, err = tx.Exec(ctx, insertSQL, task.ID, task.CreatedAt, task.DueDate, task.Subject, task.Description,
+			int64(task.Status), task.StartedAt, task.DoneAt, task.CancelledAt, max(task.Version, 1), ownerArg(ctx), parentArg(task.ParentID), task.Recurrence,
+			nextArg(task.NextID))
+	} else {
+		_, err = tx.Exec(ctx, updateSQL, task.ID, task.CreatedAt, task.DueDate, task.Subject, task.Description,
+			int64(task.Status), task.StartedAt, task.DoneAt, task.CancelledAt, parentArg(task.ParentID), task.Recurrence, nextArg(task.NextID))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = setTaskTags(ctx, tx, task.ID, tags)
+	if err != nil {
+		return false, err
+	}
+
+	return created, tx.Commit(ctx)
+}
+
+// statusUpdate returns the statement and its arguments to record the time of the status transition.
+// It updates only a task of a source state of the status.
+func statusUpdate(id uuid.UUID, owner any, status entity.TaskStatus, now time.Time) (string, []any) {
+	const scope = " WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2) AND status = ANY($4)"
+
+	sources := []int64{}
+	for _, source := range status.Sources() {
+		sources = append(sources, int64(source))
+	}
+	args := []any{id, owner, int64(status), sources}
+
+	switch status {
+	case entity.TaskStatusInProgress:
+		return "UPDATE task SET (status, started_at, version) = ($3, $5, version + 1)" + scope, append(args, now)
+	case entity.TaskStatusDone:
+		return "UPDATE task SET (status, done_at, version) = ($3, $5, version + 1)" + scope, append(args, now)
+	case entity.TaskStatusCancelled:
+		return "UPDATE task SET (status, cancelled_at, version) = ($3, $5, version + 1)" + scope, append(args, now)
+	}
+
+	return "UPDATE task SET (status, done_at, cancelled_at, version) = ($3, NULL, NULL, version + 1)" + scope, args
+}
+
+// searchConfigs maps the locale languages to their text search configuration.
+var searchConfigs = map[language.Base]string{
+	language.MustParseBase("en"): "english",
+	language.MustParseBase("de"): "german",
+}
+
+// searchConfig returns the text search configuration of the context language, simple without stemming if unsupported.
+func searchConfig(ctx context.Context) string {
+	base, _ := locale.Language(ctx).Base()
+	if config, ok := searchConfigs[base]; ok {
+		return config
+	}
+
+	return "simple"
+}
+
+// taskFilter returns the WHERE clause, the relevance column and their arguments to match the query.
+func taskFilter(ctx context.Context, query entity.TaskQuery) (string, string, []any) {
+	conditions := []string{}
+	args := []any{}
+	relevance := "0.0"
+
+	if terms := entity.SearchTerms(query.Search); len(terms) > 0 {
+		config := searchConfig(ctx)
+		args = append(args, tsQueryArg(terms))
+		conditions = append(conditions, fmt.Sprintf("search_%s @@ to_tsquery('%s', $1)", config, config))
+		relevance = fmt.Sprintf("ts_rank(search_%s, to_tsquery('%s', $1))", config, config)
+	}
+
+	if user, ok := entity.UserFromContext(ctx); ok {
+		args = append(args, user.ID)
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+
+	if len(query.Statuses) > 0 {
+		statuses := make([]int64, len(query.Statuses))
+		for s, status := range query.Statuses {
+			statuses[s] = int64(status)
+		}
+		args = append(args, statuses)
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+
+	if len(query.Tags) > 0 {
+		args = append(args, query.Tags)
+		conditions = append(conditions, fmt.Sprintf(`id IN (SELECT task_tag.task_id FROM task_tag
+			JOIN tag ON tag.id = task_tag.tag_id WHERE tag.name = ANY($%d)
+			GROUP BY task_tag.task_id HAVING count(*) = %d)`, len(args), len(query.Tags)))
+	}
+
+	if query.Blocked {
+		conditions = append(conditions, `id IN (SELECT task_dependency.task_id FROM task_dependency
+			JOIN task AS blocker ON blocker.id = task_dependency.blocker_id WHERE blocker.status IN (0, 1))`)
+	}
+
+	if len(conditions) == 0 {
+		return "", relevance, args
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), relevance, args
+}
+
+// tsQueryArg returns a text search query matching all terms as prefixes, the terms contain only letters and digits.
+func tsQueryArg(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for t, term := range terms {
+		prefixes[t] = term + ":*"
+	}
+
+	return strings.Join(prefixes, " & ")
+}
+
+// ownerArg returns the ID of the context user to scope the tasks, NULL matches the tasks of all users.
+func ownerArg(ctx context.Context) any {
+	if user, ok := entity.UserFromContext(ctx); ok {
+		return user.ID
+	}
+
+	return nil
+}
+
+// owns reports whether the task owner is the context user, all owners without user.
+func owns(ctx context.Context, owner uuid.NullUUID) bool {
+	user, ok := entity.UserFromContext(ctx)
+
+	return !ok || (owner.Valid && owner.UUID == user.ID)
+}
+
+func rollback(ctx context.Context, tx pgx.Tx) {
+	err := tx.Rollback(ctx)
+	if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		log.Error("transaction rollback failed", err)
+	}
 }
 
 func taskSort(sort entity.TaskSort) string {
@@ -171,6 +539,8 @@ func taskSort(sort entity.TaskSort) string {
 		return "due_date"
 	case entity.TaskSortSubject:
 		return "subject"
+	case entity.TaskSortRelevance:
+		return "relevance"
 	}
 
 	return "id"
@@ -184,6 +554,11 @@ func toSQLOrder(order entity.SortOrder) string {
 	return "DESC"
 }
 
+// taskOrderClause orders by the sort column and the ID to get a stable paging.
 func taskOrderClause(sort entity.TaskSort, order entity.SortOrder) string {
-	return fmt.Sprintf("%s %s", taskSort(sort), toSQLOrder(order))
+	if sort == entity.TaskSortRelevance { // most relevant first in both orders
+		return "relevance DESC, id " + toSQLOrder(order)
+	}
+
+	return fmt.Sprintf("%s %s, id %s", taskSort(sort), toSQLOrder(order), toSQLOrder(order))
 }
@@ -0,0 +1,276 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/entity/storagetest"
+	"github.com/dgf/go-ssr-x/postgres"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// dsnEnv names the URL of a PostgreSQL test database, e.g. postgres://task-db-user@localhost/tasks_test, the tests
+// start an embedded PostgreSQL without it.
+const dsnEnv = "TASKS_TEST_POSTGRES_DSN"
+
+// testDSN is the URL of the test database, empty if neither set nor started.
+var testDSN string
+
+// schemas counts the created schemas of the test run.
+var schemas int
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+// TestDatabase migrates a fresh schema for every check and drops it afterwards.
+func TestDatabase(t *testing.T) {
+	if testDSN == "" {
+		t.Skip("no PostgreSQL, set " + dsnEnv + " or allow the embedded PostgreSQL download")
+	}
+
+	storagetest.Run(t, func(ctx context.Context) (entity.Storage, error) {
+		return openSchema(ctx, t)
+	})
+}
+
+// TestTaskEvents checks the owner of the notified task and dependency changes.
+func TestTaskEvents(t *testing.T) {
+	if testDSN == "" {
+		t.Skip("no PostgreSQL, set " + dsnEnv + " or allow the embedded PostgreSQL download")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	db, err := openSchema(ctx, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	alice, err := db.AddUser(ctx, "alice", "alice password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceCtx := entity.WithUser(ctx, alice)
+
+	hub := entity.NewHub()
+	events, unsubscribe := hub.Subscribe(alice.ID)
+	defer unsubscribe()
+
+	go func() { _ = db.ListenTaskEvents(ctx, hub) }()
+	awaitListening(aliceCtx, t, db, events)
+
+	ids := make([]uuid.UUID, 2)
+	for i := range ids {
+		ids[i], err = db.AddTask(aliceCtx, entity.TaskData{Subject: fmt.Sprintf("task %d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		awaitEvent(ctx, t, events, entity.TaskEvent{Type: entity.TaskCreated, ID: ids[i], OwnerID: alice.ID})
+	}
+
+	err = db.AddDependency(aliceCtx, ids[0], ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		awaitEvent(ctx, t, events, entity.TaskEvent{Type: entity.TaskUpdated, ID: id, OwnerID: alice.ID})
+	}
+
+	err = db.RemoveDependency(aliceCtx, ids[0], ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		awaitEvent(ctx, t, events, entity.TaskEvent{Type: entity.TaskUpdated, ID: id, OwnerID: alice.ID})
+	}
+
+	tag, err := db.AddTag(aliceCtx, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = db.RenameTag(aliceCtx, tag.ID, "devops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(ctx, t, events, entity.TaskEvent{Type: entity.TasksChanged, OwnerID: alice.ID})
+}
+
+// awaitListening adds tasks until an event arrives, the listener misses the changes before its LISTEN.
+func awaitListening(ctx context.Context, t *testing.T, db *postgres.Database, events <-chan entity.TaskEvent) {
+	t.Helper()
+
+	for {
+		_, err := db.AddTask(ctx, entity.TaskData{Subject: "listening"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("task events listening failed")
+		case <-events:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// awaitEvent skips the other events until the event arrives, it fails if the context is done first.
+func awaitEvent(ctx context.Context, t *testing.T, events <-chan entity.TaskEvent, want entity.TaskEvent) {
+	t.Helper()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("event %+v missing", want)
+		case event := <-events:
+			if event == want {
+				return
+			}
+		}
+	}
+}
+
+// openSchema opens the database in a fresh schema that is dropped after the test.
+func openSchema(ctx context.Context, t *testing.T) (*postgres.Database, error) {
+	t.Helper()
+
+	schemas++
+	schema := fmt.Sprintf("storagetest_%d_%d", os.Getpid(), schemas)
+
+	schemaDSN, err := createSchema(ctx, testDSN, schema)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { dropSchema(t, testDSN, schema) })
+
+	return postgres.NewDatabase(ctx, schemaDSN)
+}
+
+// runTests runs the tests against the database of the DSN environment variable or an embedded PostgreSQL.
+func runTests(m *testing.M) int {
+	testDSN = os.Getenv(dsnEnv)
+	if testDSN == "" {
+		dsn, stop, err := startEmbedded()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "embedded PostgreSQL start failed: %v\n", err)
+		} else {
+			testDSN = dsn
+			defer stop()
+		}
+	}
+
+	return m.Run()
+}
+
+// startEmbedded starts a PostgreSQL in a temporary directory on a free port and returns its DSN, the binaries are
+// downloaded once.
+func startEmbedded() (string, func(), error) {
+	port, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "tasks-postgres-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		Port(port).
+		Database("tasks_test").
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		Logger(io.Discard)
+	db := embeddedpostgres.NewDatabase(config)
+
+	err = db.Start()
+	if err != nil {
+		return "", nil, errors.Join(err, os.RemoveAll(dir))
+	}
+
+	return config.GetConnectionURL() + "?sslmode=disable", func() {
+		err := errors.Join(db.Stop(), os.RemoveAll(dir))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "embedded PostgreSQL stop failed: %v\n", err)
+		}
+	}, nil
+}
+
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return 0, err
+	}
+
+	p, err := strconv.ParseUint(port, 10, 32)
+
+	return uint32(p), err
+}
+
+// createSchema returns the DSN of the new schema.
+func createSchema(ctx context.Context, dsn, schema string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "CREATE SCHEMA "+schema)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func dropSchema(t *testing.T, dsn, schema string) {
+	t.Helper()
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Error(err)
+
+		return
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+	if err != nil {
+		t.Error(err)
+	}
+}
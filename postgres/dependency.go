@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AddDependency locks the dependencies to check the cycles, concurrent inserts could close a cycle otherwise.
+func (d *Database) AddDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	const (
+		lockSQL  = "LOCK TABLE task_dependency IN SHARE ROW EXCLUSIVE MODE"
+		taskSQL  = "SELECT count(*) FROM task WHERE id IN ($1, $2) AND ($3::uuid IS NULL OR owner_id = $3)"
+		cycleSQL = `WITH RECURSIVE blocker(id) AS (
+				SELECT $1::uuid
+				UNION
+				SELECT task_dependency.blocker_id FROM task_dependency JOIN blocker ON task_dependency.task_id = blocker.id
+			)
+			SELECT count(*) FROM blocker WHERE id = $2`
+		insertSQL = "INSERT INTO task_dependency (task_id, blocker_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	)
+
+	if id == blockerID {
+		return fmt.Errorf("task %s blocks itself: %w", id, entity.ErrDependencyCycle)
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback(ctx, tx)
+
+	_, err = tx.Exec(ctx, lockSQL)
+	if err != nil {
+		return err
+	}
+
+	var tasks int
+	err = tx.QueryRow(ctx, taskSQL, id, blockerID, ownerArg(ctx)).Scan(&tasks)
+	if err != nil {
+		return err
+	}
+
+	if tasks != 2 {
+		return fmt.Errorf("no row for %s or %s: %w", id, blockerID, entity.ErrNotFound)
+	}
+
+	var cycles int
+	err = tx.QueryRow(ctx, cycleSQL, blockerID, id).Scan(&cycles)
+	if err != nil {
+		return err
+	}
+
+	if cycles > 0 {
+		return fmt.Errorf("task %s depends on %s: %w", blockerID, id, entity.ErrDependencyCycle)
+	}
+
+	_, err = tx.Exec(ctx, insertSQL, id, blockerID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (d *Database) RemoveDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	const sql = `DELETE FROM task_dependency WHERE task_id = $1 AND blocker_id = $2
+		AND task_id IN (SELECT id FROM task WHERE ($3::uuid IS NULL OR owner_id = $3))`
+
+	tag, err := d.db.Exec(ctx, sql, id, blockerID, ownerArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("no dependency of %s on %s: %w", id, blockerID, entity.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (d *Database) Dependencies(ctx context.Context, id uuid.UUID) (entity.TaskDependencies, error) {
+	const (
+		columns = `SELECT task.id, created_at, due_date, subject, status, ` + tagsColumn + `, description, ` + dependencyColumns
+		scope   = ` AND ($2::uuid IS NULL OR owner_id = $2) ORDER BY created_at, task.id`
+
+		blockedBySQL = columns + ` FROM task_dependency JOIN task ON task.id = task_dependency.blocker_id
+			WHERE task_dependency.task_id = $1` + scope
+		blockingSQL = columns + ` FROM task_dependency JOIN task ON task.id = task_dependency.task_id
+			WHERE task_dependency.blocker_id = $1` + scope
+	)
+
+	tx, err := d.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return entity.TaskDependencies{}, err
+	}
+	defer rollback(ctx, tx)
+
+	blockedBy, err := queryTaskOverviews(ctx, tx, blockedBySQL, id, ownerArg(ctx))
+	if err != nil {
+		return entity.TaskDependencies{}, err
+	}
+
+	blocking, err := queryTaskOverviews(ctx, tx, blockingSQL, id, ownerArg(ctx))
+	if err != nil {
+		return entity.TaskDependencies{}, err
+	}
+
+	return entity.TaskDependencies{BlockedBy: blockedBy, Blocking: blocking}, nil
+}
+
+func (d *Database) WorkPlan(ctx context.Context) ([]entity.TaskOverview, error) {
+	const (
+		tasksSQL = `SELECT task.id, created_at, due_date, subject, status, ` + tagsColumn + `, description, ` + dependencyColumns + `
+			FROM task WHERE status IN (0, 1) AND ($1::uuid IS NULL OR owner_id = $1)`
+		blockersSQL = `SELECT task_id, blocker_id FROM task_dependency JOIN task ON task.id = task_dependency.task_id
+			WHERE status IN (0, 1) AND ($1::uuid IS NULL OR owner_id = $1)`
+	)
+
+	tx, err := d.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	tasks, err := queryTaskOverviews(ctx, tx, tasksSQL, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, blockersSQL, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	blockers := map[uuid.UUID][]uuid.UUID{}
+	var id, blockerID uuid.UUID
+	_, err = pgx.ForEachRow(rows, []any{&id, &blockerID}, func() error {
+		blockers[id] = append(blockers[id], blockerID)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.SortWorkPlan(tasks, blockers), nil
+}
+
+func queryTaskOverviews(ctx context.Context, tx pgx.Tx, sql string, args ...any) ([]entity.TaskOverview, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	overviewRows, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[taskOverviewRow])
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]entity.TaskOverview, len(overviewRows))
+	for t, row := range overviewRows {
+		row.Checklist = entity.ParseChecklist(row.Description)
+		tasks[t] = row.TaskOverview
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/google/uuid"
+)
+
+// taskEventsChannel is notified by the task table trigger of every change.
+const taskEventsChannel = "task_events"
+
+type taskNotification struct {
+	Type  string        `json:"type"`
+	ID    uuid.UUID     `json:"id"`
+	Owner uuid.NullUUID `json:"owner"`
+}
+
+// ListenTaskEvents publishes the task changes of all database clients to the hub.
+func (d *Database) ListenTaskEvents(ctx context.Context, hub *entity.Hub) error {
+	conn, err := d.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN "+taskEventsChannel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("task events listening failed: %w", err)
+		}
+
+		var n taskNotification
+		err = json.Unmarshal([]byte(notification.Payload), &n)
+		if err != nil {
+			log.Warn(fmt.Sprintf("task event decoding failed: %v", err))
+
+			continue
+		}
+
+		eventType, ok := entity.ParseTaskEventType(n.Type)
+		if !ok {
+			log.Warn("unknown task event type", "type", n.Type)
+
+			continue
+		}
+
+		hub.Publish(entity.TaskEvent{Type: eventType, ID: n.ID, OwnerID: n.Owner.UUID})
+	}
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (d *Database) AddTag(ctx context.Context, name string) (entity.Tag, error) {
+	tag, err := entity.NormalizeTag(name)
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	id, err := ensureTag(ctx, d.db, ownerArg(ctx), tag)
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	return entity.Tag{ID: id, Name: tag}, nil
+}
+
+func (d *Database) Tags(ctx context.Context) ([]entity.Tag, error) {
+	const sql = "SELECT id, name FROM tag WHERE $1::uuid IS NULL OR owner_id = $1 ORDER BY name"
+
+	rows, err := d.db.Query(ctx, sql, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[entity.Tag])
+}
+
+func (d *Database) RenameTag(ctx context.Context, id uuid.UUID, name string) (entity.Tag, bool, error) {
+	const (
+		ownerSQL  = "SELECT owner_id FROM tag WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2)"
+		existsSQL = "SELECT id FROM tag WHERE name = $1 AND owner_id IS NOT DISTINCT FROM $2"
+		updateSQL = "UPDATE tag SET name = $2 WHERE id = $1"
+	)
+
+	tag, err := entity.NormalizeTag(name)
+	if err != nil {
+		return entity.Tag{}, false, err
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return entity.Tag{}, false, err
+	}
+	defer rollback(ctx, tx)
+
+	var owner uuid.NullUUID
+	err = tx.QueryRow(ctx, ownerSQL, id, ownerArg(ctx)).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Tag{}, false, nil
+	} else if err != nil {
+		return entity.Tag{}, false, err
+	}
+
+	var existing uuid.UUID
+	err = tx.QueryRow(ctx, existsSQL, tag, owner).Scan(&existing)
+	if err == nil && existing != id {
+		return entity.Tag{}, false, fmt.Errorf("%w: %s", entity.ErrTagExists, tag)
+	} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return entity.Tag{}, false, err
+	}
+
+	result, err := tx.Exec(ctx, updateSQL, id, tag)
+	if err != nil {
+		return entity.Tag{}, false, err
+	}
+
+	if result.RowsAffected() != 1 {
+		return entity.Tag{}, false, nil
+	}
+
+	return entity.Tag{ID: id, Name: tag}, true, tx.Commit(ctx)
+}
+
+func (d *Database) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	const sql = "DELETE FROM tag WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2)"
+
+	tag, err := d.db.Exec(ctx, sql, id, ownerArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("no tag for %s: %w", id, entity.ErrNotFound)
+	}
+
+	return nil
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// ensureTag returns the ID of the named tag of the owner, it creates missing tags.
+func ensureTag(ctx context.Context, q querier, owner any, name string) (uuid.UUID, error) {
+	const sql = `INSERT INTO tag (id, name, owner_id) VALUES ($1, $2, $3)
+		ON CONFLICT (coalesce(owner_id, '00000000-0000-0000-0000-000000000000'), name) DO UPDATE SET name = excluded.name
+		RETURNING id`
+
+	var id uuid.UUID
+	err := q.QueryRow(ctx, sql, uuid.New(), name, owner).Scan(&id)
+
+	return id, err
+}
+
+// setTaskTags replaces the tags of the task with the named tags of the task owner.
+func setTaskTags(ctx context.Context, tx pgx.Tx, id uuid.UUID, tags []string) error {
+	const (
+		ownerSQL  = "SELECT owner_id FROM task WHERE id = $1"
+		deleteSQL = "DELETE FROM task_tag WHERE task_id = $1"
+		insertSQL = "INSERT INTO task_tag (task_id, tag_id) VALUES ($1, $2)"
+	)
+
+	var owner uuid.NullUUID
+	err := tx.QueryRow(ctx, ownerSQL, id).Scan(&owner)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, deleteSQL, id)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		tagID, err := ensureTag(ctx, tx, owner, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, insertSQL, id, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
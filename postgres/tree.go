@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// subtaskRow is a descendant with the description of its checklist.
+type subtaskRow struct {
+	taskOverviewRow
+
+	ParentID uuid.UUID
+	Depth    int
+}
+
+func (d *Database) Subtasks(ctx context.Context, id uuid.UUID) ([]entity.Subtask, error) {
+	const sql = `WITH RECURSIVE subtask(id, parent_id, depth) AS (
+			SELECT id, parent_id, 1 FROM task WHERE parent_id = $1
+			UNION ALL
+			SELECT task.id, task.parent_id, subtask.depth + 1 FROM task JOIN subtask ON task.parent_id = subtask.id
+		)
+		SELECT task.id, created_at, due_date, subject, status, ` + tagsColumn + `, description, ` + dependencyColumns + `, subtask.parent_id, subtask.depth
+		FROM subtask JOIN task ON task.id = subtask.id WHERE ($2::uuid IS NULL OR owner_id = $2)`
+
+	rows, err := d.db.Query(ctx, sql, id, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	subtaskRows, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[subtaskRow])
+	if err != nil {
+		return nil, err
+	}
+
+	subtasks := make([]entity.Subtask, len(subtaskRows))
+	for s, row := range subtaskRows {
+		row.Checklist = entity.ParseChecklist(row.Description)
+		subtasks[s] = entity.Subtask{TaskOverview: row.TaskOverview, ParentID: row.ParentID, Depth: row.Depth}
+	}
+
+	return entity.SortSubtasks(id, subtasks), nil
+}
+
+func (d *Database) Ancestors(ctx context.Context, id uuid.UUID) ([]entity.TaskOverview, error) {
+	const sql = `WITH RECURSIVE ancestor(id, depth) AS (
+			SELECT parent_id, 1 FROM task WHERE id = $1 AND parent_id IS NOT NULL
+			UNION ALL
+			SELECT task.parent_id, ancestor.depth + 1 FROM task JOIN ancestor ON task.id = ancestor.id WHERE task.parent_id IS NOT NULL
+		)
+		SELECT task.id, created_at, due_date, subject, status, ` + tagsColumn + `, description, ` + dependencyColumns + `
+		FROM ancestor JOIN task ON task.id = ancestor.id WHERE ($2::uuid IS NULL OR owner_id = $2) ORDER BY ancestor.depth`
+
+	rows, err := d.db.Query(ctx, sql, id, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	overviewRows, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[taskOverviewRow])
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := make([]entity.TaskOverview, len(overviewRows))
+	for a, row := range overviewRows {
+		row.Checklist = entity.ParseChecklist(row.Description)
+		ancestors[a] = row.TaskOverview
+	}
+
+	return ancestors, nil
+}
+
+func (d *Database) DeleteTaskTree(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	const sql = `WITH RECURSIVE tree(id) AS (
+			SELECT id FROM task WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2)
+			UNION ALL
+			SELECT task.id FROM task JOIN tree ON task.parent_id = tree.id
+		)
+		DELETE FROM task WHERE id IN (SELECT id FROM tree) RETURNING id`
+
+	rows, err := d.db.Query(ctx, sql, id, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no row for %s: %w", id, entity.ErrNotFound)
+	}
+
+	return ids, nil
+}
+
+// checkParent returns an ErrInvalidParent error if the parent is missing or a subtask of the task, a task without parent is valid.
+func checkParent(ctx context.Context, tx pgx.Tx, id, parentID uuid.UUID) error {
+	const sql = `WITH RECURSIVE ancestor(id) AS (
+			SELECT id FROM task WHERE id = $1 AND ($2::uuid IS NULL OR owner_id = $2)
+			UNION
+			SELECT task.parent_id FROM task JOIN ancestor ON task.id = ancestor.id WHERE task.parent_id IS NOT NULL
+		)
+		SELECT count(*), count(*) FILTER (WHERE id = $3) FROM ancestor`
+
+	if parentID == uuid.Nil {
+		return nil
+	}
+
+	var ancestors, cycles int
+	err := tx.QueryRow(ctx, sql, parentID, ownerArg(ctx), id).Scan(&ancestors, &cycles)
+	if err != nil {
+		return err
+	}
+
+	if ancestors == 0 {
+		return fmt.Errorf("parent %s: %w", parentID, entity.ErrInvalidParent)
+	}
+
+	if cycles > 0 {
+		return fmt.Errorf("parent %s is a subtask of %s: %w", parentID, id, entity.ErrInvalidParent)
+	}
+
+	return nil
+}
+
+// parentArg returns the parent ID, NULL of a top-level task.
+func parentArg(parentID uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: parentID, Valid: parentID != uuid.Nil}
+}
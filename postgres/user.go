@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (d *Database) AddUser(ctx context.Context, name, password string) (entity.User, error) {
+	const sql = `INSERT INTO users (id, name, password_hash, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO NOTHING`
+
+	normalized, err := entity.NormalizeUserName(name)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	hash, err := entity.HashPassword(password)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user := entity.User{ID: uuid.New(), Name: normalized, PasswordHash: hash, CreatedAt: time.Now()}
+	tag, err := d.db.Exec(ctx, sql, user.ID, user.Name, user.PasswordHash, user.CreatedAt)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if tag.RowsAffected() != 1 {
+		return entity.User{}, fmt.Errorf("%w: %s", entity.ErrUserExists, normalized)
+	}
+
+	return user, nil
+}
+
+func (d *Database) User(ctx context.Context, id uuid.UUID) (entity.User, bool, error) {
+	const sql = "SELECT id, name, password_hash, created_at FROM users WHERE id = $1"
+
+	return d.queryUser(ctx, sql, id)
+}
+
+func (d *Database) UserByName(ctx context.Context, name string) (entity.User, bool, error) {
+	const sql = "SELECT id, name, password_hash, created_at FROM users WHERE name = $1"
+
+	normalized, err := entity.NormalizeUserName(name)
+	if err != nil {
+		return entity.User{}, false, nil // an invalid name is unknown
+	}
+
+	return d.queryUser(ctx, sql, normalized)
+}
+
+// ClaimTasks passes the tasks without user to the user, their tags merge by name into the tags of the user.
+func (d *Database) ClaimTasks(ctx context.Context, userID uuid.UUID) (int, error) {
+	const (
+		claimSQL = "UPDATE task SET owner_id = $1 WHERE owner_id IS NULL"
+		mergeSQL = `UPDATE task_tag SET tag_id = owned.id FROM tag, tag AS owned
+			WHERE tag.id = task_tag.tag_id AND tag.owner_id IS NULL AND owned.name = tag.name AND owned.owner_id = $1`
+		deleteSQL = "DELETE FROM tag WHERE owner_id IS NULL AND name IN (SELECT name FROM tag WHERE owner_id = $1)"
+		tagSQL    = "UPDATE tag SET owner_id = $1 WHERE owner_id IS NULL"
+	)
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer rollback(ctx, tx)
+
+	tag, err := tx.Exec(ctx, claimSQL, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sql := range []string{mergeSQL, deleteSQL, tagSQL} {
+		_, err = tx.Exec(ctx, sql, userID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int(tag.RowsAffected()), tx.Commit(ctx)
+}
+
+func (d *Database) queryUser(ctx context.Context, sql string, arg any) (entity.User, bool, error) {
+	rows, err := d.db.Query(ctx, sql, arg)
+	if err != nil {
+		return entity.User{}, false, err
+	}
+
+	user, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entity.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.User{}, false, nil
+		}
+
+		return entity.User{}, false, err
+	}
+
+	return user, true, nil
+}
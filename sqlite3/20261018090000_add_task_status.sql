@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE task ADD COLUMN status integer NOT NULL DEFAULT 0;
+ALTER TABLE task ADD COLUMN started_at timestamp;
+ALTER TABLE task ADD COLUMN done_at timestamp;
+ALTER TABLE task ADD COLUMN cancelled_at timestamp;
+CREATE INDEX task_status_idx ON task (status);
+
+-- +goose Down
+DROP INDEX task_status_idx;
+ALTER TABLE task DROP COLUMN cancelled_at;
+ALTER TABLE task DROP COLUMN done_at;
+ALTER TABLE task DROP COLUMN started_at;
+ALTER TABLE task DROP COLUMN status;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tag (
+    id uuid PRIMARY KEY,
+    name varchar(32) NOT NULL UNIQUE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE task_tag (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    tag_id uuid NOT NULL REFERENCES tag (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+-- +goose StatementEnd
+
+CREATE INDEX task_tag_tag_idx ON task_tag (tag_id);
+
+-- +goose Down
+DROP TABLE task_tag;
+DROP TABLE tag;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE VIRTUAL TABLE task_fts USING fts5(
+    id UNINDEXED,
+    subject,
+    description,
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+INSERT INTO task_fts (id, subject, description) SELECT id, subject, description FROM task;
+
+-- +goose StatementBegin
+CREATE TRIGGER task_fts_insert AFTER INSERT ON task BEGIN
+    INSERT INTO task_fts (id, subject, description) VALUES (new.id, new.subject, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER task_fts_delete AFTER DELETE ON task BEGIN
+    DELETE FROM task_fts WHERE id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER task_fts_update AFTER UPDATE OF subject, description ON task BEGIN
+    UPDATE task_fts SET (subject, description) = (new.subject, new.description) WHERE id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER task_fts_update;
+DROP TRIGGER task_fts_delete;
+DROP TRIGGER task_fts_insert;
+DROP TABLE task_fts;
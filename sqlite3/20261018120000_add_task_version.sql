@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE task ADD COLUMN version integer NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE task DROP COLUMN version;
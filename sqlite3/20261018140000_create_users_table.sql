@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE users (
+    id uuid PRIMARY KEY,
+    name varchar(32) NOT NULL UNIQUE,
+    password_hash blob NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+ALTER TABLE task ADD COLUMN owner_id uuid REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX task_owner_idx ON task (owner_id);
+
+-- +goose Down
+DROP INDEX task_owner_idx;
+ALTER TABLE task DROP COLUMN owner_id;
+DROP TABLE users;
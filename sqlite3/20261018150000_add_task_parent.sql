@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE task ADD COLUMN parent_id uuid REFERENCES task (id) ON DELETE SET NULL;
+
+CREATE INDEX task_parent_idx ON task (parent_id);
+
+-- +goose Down
+DROP INDEX task_parent_idx;
+ALTER TABLE task DROP COLUMN parent_id;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE task_dependency (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    blocker_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, blocker_id),
+    CHECK (task_id <> blocker_id)
+);
+
+CREATE INDEX task_dependency_blocker_idx ON task_dependency (blocker_id);
+
+-- +goose Down
+DROP TABLE task_dependency;
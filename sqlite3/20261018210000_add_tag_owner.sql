@@ -0,0 +1,79 @@
+-- +goose Up
+-- the tags are split by the owners of their tasks, the first owner keeps the tag ID
+-- +goose StatementBegin
+CREATE TABLE tag_split AS
+SELECT tag_id, owner_id, CASE WHEN row_number() OVER (PARTITION BY tag_id ORDER BY owner_id) = 1 THEN tag_id
+    ELSE lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))) END AS id
+FROM (SELECT DISTINCT task_tag.tag_id, task.owner_id FROM task_tag JOIN task ON task.id = task_tag.task_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE owned_tag (
+    id uuid PRIMARY KEY,
+    name varchar(32) NOT NULL,
+    owner_id uuid REFERENCES users (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO owned_tag (id, name, owner_id)
+SELECT tag_split.id, tag.name, tag_split.owner_id FROM tag_split JOIN tag ON tag.id = tag_split.tag_id
+UNION ALL
+SELECT id, name, NULL FROM tag WHERE id NOT IN (SELECT tag_id FROM tag_split);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE owned_task_tag (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    tag_id uuid NOT NULL REFERENCES owned_tag (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO owned_task_tag (task_id, tag_id)
+SELECT task_tag.task_id, tag_split.id FROM task_tag JOIN task ON task.id = task_tag.task_id
+    JOIN tag_split ON tag_split.tag_id = task_tag.tag_id AND tag_split.owner_id IS task.owner_id;
+-- +goose StatementEnd
+
+DROP TABLE tag_split;
+DROP TABLE task_tag;
+DROP TABLE tag;
+ALTER TABLE owned_tag RENAME TO tag;
+ALTER TABLE owned_task_tag RENAME TO task_tag;
+
+CREATE INDEX task_tag_tag_idx ON task_tag (tag_id);
+CREATE UNIQUE INDEX tag_owner_name_idx ON tag (coalesce(owner_id, ''), name);
+
+-- +goose Down
+-- the tags of all owners are merged by name
+-- +goose StatementBegin
+CREATE TABLE shared_tag (
+    id uuid PRIMARY KEY,
+    name varchar(32) NOT NULL UNIQUE
+);
+-- +goose StatementEnd
+
+INSERT INTO shared_tag (id, name) SELECT min(id), name FROM tag GROUP BY name;
+
+-- +goose StatementBegin
+CREATE TABLE shared_task_tag (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    tag_id uuid NOT NULL REFERENCES shared_tag (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO shared_task_tag (task_id, tag_id)
+SELECT task_tag.task_id, shared_tag.id FROM task_tag JOIN tag ON tag.id = task_tag.tag_id
+    JOIN shared_tag ON shared_tag.name = tag.name;
+-- +goose StatementEnd
+
+DROP TABLE task_tag;
+DROP TABLE tag;
+ALTER TABLE shared_tag RENAME TO tag;
+ALTER TABLE shared_task_tag RENAME TO task_tag;
+
+CREATE INDEX task_tag_tag_idx ON task_tag (tag_id);
@@ -0,0 +1,164 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+func (f *File) AddDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	const (
+		taskQuery  = "SELECT count(*) FROM task WHERE id IN ($1, $2) AND ($3 IS NULL OR owner_id = $3)"
+		cycleQuery = `WITH RECURSIVE blocker(id) AS (
+				SELECT $1
+				UNION
+				SELECT task_dependency.blocker_id FROM task_dependency JOIN blocker ON task_dependency.task_id = blocker.id
+			)
+			SELECT count(*) FROM blocker WHERE id = $2`
+		insertQuery = "INSERT INTO task_dependency (task_id, blocker_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	)
+
+	if id == blockerID {
+		return fmt.Errorf("task %s blocks itself: %w", id, entity.ErrDependencyCycle)
+	}
+
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	var tasks int
+	err = tx.QueryRowContext(ctx, taskQuery, id, blockerID, ownerArg(ctx)).Scan(&tasks)
+	if err != nil {
+		return err
+	}
+
+	if tasks != 2 {
+		return fmt.Errorf("no row for %s or %s: %w", id, blockerID, entity.ErrNotFound)
+	}
+
+	var cycles int
+	err = tx.QueryRowContext(ctx, cycleQuery, blockerID, id).Scan(&cycles)
+	if err != nil {
+		return err
+	}
+
+	if cycles > 0 {
+		return fmt.Errorf("task %s depends on %s: %w", blockerID, id, entity.ErrDependencyCycle)
+	}
+
+	_, err = tx.ExecContext(ctx, insertQuery, id, blockerID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (f *File) RemoveDependency(ctx context.Context, id, blockerID uuid.UUID) error {
+	const query = `DELETE FROM task_dependency WHERE task_id = $1 AND blocker_id = $2
+		AND task_id IN (SELECT id FROM task WHERE ($3 IS NULL OR owner_id = $3))`
+
+	result, err := f.db.ExecContext(ctx, query, id, blockerID, ownerArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows access failed: %w", err)
+	}
+
+	if rows != 1 {
+		return fmt.Errorf("no dependency of %s on %s: %w", id, blockerID, entity.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (f *File) Dependencies(ctx context.Context, id uuid.UUID) (entity.TaskDependencies, error) {
+	const (
+		columns = `SELECT task.id, created_at, due_date, subject, status, 0.0, ` + tagsColumn + `, description, ` + dependencyColumns
+		scope   = ` AND ($2 IS NULL OR owner_id = $2) ORDER BY created_at, task.id`
+
+		blockedByQuery = columns + ` FROM task_dependency JOIN task ON task.id = task_dependency.blocker_id
+			WHERE task_dependency.task_id = $1` + scope
+		blockingQuery = columns + ` FROM task_dependency JOIN task ON task.id = task_dependency.task_id
+			WHERE task_dependency.blocker_id = $1` + scope
+	)
+
+	tx, err := f.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return entity.TaskDependencies{}, err
+	}
+	defer rollback(tx)
+
+	blockedBy, err := queryTaskOverviews(ctx, tx, blockedByQuery, id, ownerArg(ctx))
+	if err != nil {
+		return entity.TaskDependencies{}, err
+	}
+
+	blocking, err := queryTaskOverviews(ctx, tx, blockingQuery, id, ownerArg(ctx))
+	if err != nil {
+		return entity.TaskDependencies{}, err
+	}
+
+	return entity.TaskDependencies{BlockedBy: blockedBy, Blocking: blocking}, nil
+}
+
+func (f *File) WorkPlan(ctx context.Context) ([]entity.TaskOverview, error) {
+	const (
+		tasksQuery = `SELECT task.id, created_at, due_date, subject, status, 0.0, ` + tagsColumn + `, description, ` + dependencyColumns + `
+			FROM task WHERE status IN (0, 1) AND ($1 IS NULL OR owner_id = $1)`
+		blockersQuery = `SELECT task_id, blocker_id FROM task_dependency JOIN task ON task.id = task_dependency.task_id
+			WHERE status IN (0, 1) AND ($1 IS NULL OR owner_id = $1)`
+	)
+
+	tx, err := f.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(tx)
+
+	tasks, err := queryTaskOverviews(ctx, tx, tasksQuery, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, blockersQuery, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blockers := map[uuid.UUID][]uuid.UUID{}
+	for rows.Next() {
+		var id, blockerID uuid.UUID
+		err = rows.Scan(&id, &blockerID)
+		if err != nil {
+			return nil, err
+		}
+		blockers[id] = append(blockers[id], blockerID)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.SortWorkPlan(tasks, blockers), nil
+}
+
+func queryTaskOverviews(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]entity.TaskOverview, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTaskOverviews(rows)
+}
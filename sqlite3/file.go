@@ -7,12 +7,16 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"iter"
 	"strings"
+	"time"
 
 	"github.com/dgf/go-ssr-x/entity"
 	"github.com/dgf/go-ssr-x/log"
 	"github.com/google/uuid"
 	"github.com/pressly/goose/v3"
+
+	_ "modernc.org/sqlite"
 )
 
 //go:embed *.sql
@@ -22,8 +26,32 @@ type File struct {
 	db *sql.DB
 }
 
+// tagsColumn selects the comma separated tag names of a task.
+const tagsColumn = `(SELECT group_concat(tag.name, ',') FROM tag
+	JOIN task_tag ON tag.id = task_tag.tag_id WHERE task_tag.task_id = task.id)`
+
+// dependencyColumns count the unfinished (open and in progress) blockers of a task and the unfinished tasks it blocks.
+const dependencyColumns = `(SELECT count(*) FROM task_dependency JOIN task AS blocker ON blocker.id = task_dependency.blocker_id
+		WHERE task_dependency.task_id = task.id AND blocker.status IN (0, 1)),
+	(SELECT count(*) FROM task_dependency JOIN task AS blocked ON blocked.id = task_dependency.task_id
+		WHERE task_dependency.blocker_id = task.id AND blocked.status IN (0, 1) AND task.status IN (0, 1))`
+
+// Scheme of the file DSNs, e.g. sqlite:///var/lib/tasks.sqlite or sqlite://tasks.sqlite for a relative path.
+const Scheme = "sqlite"
+
+func init() {
+	entity.Register(Scheme, func(ctx context.Context, dsn string) (entity.Storage, error) {
+		file, err := NewFile(ctx, strings.TrimPrefix(dsn, Scheme+"://"))
+		if err != nil {
+			return nil, err
+		}
+
+		return file, nil
+	})
+}
+
 func NewFile(ctx context.Context, dsn string) (*File, error) {
-	db, err := sql.Open("sqlite", dsn)
+	db, err := sql.Open("sqlite", withPragmas(dsn))
 	if err != nil {
 		return nil, err
 	}
@@ -41,48 +69,91 @@ func NewFile(ctx context.Context, dsn string) (*File, error) {
 	return &File{db: db}, nil
 }
 
+func (f *File) Ping(ctx context.Context) error {
+	return f.db.PingContext(ctx)
+}
+
 func (f *File) Close() error {
 	return f.db.Close()
 }
 
 func (f *File) AddTask(ctx context.Context, data entity.TaskData) (uuid.UUID, error) {
-	const query = "INSERT INTO task (id, due_date, subject, description) VALUES ($1, $2, $3, $4)"
+	const query = "INSERT INTO task (id, due_date, subject, description, owner_id, parent_id, recurrence) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	tags, err := entity.NormalizeTags(data.Tags)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer rollback(tx)
 
 	id := uuid.New()
-	_, err := f.db.ExecContext(ctx, query, id, data.DueDate, data.Subject, data.Description)
+	err = checkParent(ctx, tx, id, data.ParentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, query, id, data.DueDate, data.Subject, data.Description, ownerArg(ctx), parentArg(data.ParentID), data.Recurrence)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = setTaskTags(ctx, tx, id, tags)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	return id, nil
+	return id, tx.Commit()
 }
 
 func (f *File) DeleteTask(ctx context.Context, id uuid.UUID) error {
-	const query = "DELETE FROM task WHERE id = $1"
+	const (
+		parentQuery   = "SELECT parent_id FROM task WHERE id = $1 AND ($2 IS NULL OR owner_id = $2)"
+		reparentQuery = "UPDATE task SET parent_id = $2 WHERE parent_id = $1"
+		deleteQuery   = "DELETE FROM task WHERE id = $1"
+	)
 
-	result, err := f.db.ExecContext(ctx, query, id)
+	tx, err := f.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer rollback(tx)
 
-	rows, err := result.RowsAffected()
+	var parent uuid.NullUUID
+	err = tx.QueryRowContext(ctx, parentQuery, id, ownerArg(ctx)).Scan(&parent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no row for %s: %w", id, entity.ErrNotFound)
+	}
 	if err != nil {
-		return fmt.Errorf("rows access failed: %w", err)
+		return err
 	}
 
-	if rows != 1 {
-		return fmt.Errorf("no row for %s", id)
+	_, err = tx.ExecContext(ctx, reparentQuery, id, parent)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	_, err = tx.ExecContext(ctx, deleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (f *File) Task(ctx context.Context, id uuid.UUID) (entity.Task, bool, error) {
-	const query = "SELECT created_at, due_date, subject, description FROM task WHERE id = $1"
+	const query = `SELECT created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, parent_id, recurrence, next_id, ` +
+		tagsColumn + ` FROM task WHERE id = $1 AND ($2 IS NULL OR owner_id = $2)`
 
 	var task entity.Task
-	row := f.db.QueryRowContext(ctx, query, id)
-	err := row.Scan(&task.CreatedAt, &task.DueDate, &task.Subject, &task.Description)
+	var tags sql.NullString
+	row := f.db.QueryRowContext(ctx, query, id, ownerArg(ctx))
+	err := row.Scan(&task.CreatedAt, &task.DueDate, &task.Subject, &task.Description,
+		&task.Status, &task.StartedAt, &task.DoneAt, &task.CancelledAt, &task.Version, &task.ParentID, &task.Recurrence, &task.NextID, &tags)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return task, false, nil
@@ -92,15 +163,16 @@ func (f *File) Task(ctx context.Context, id uuid.UUID) (entity.Task, bool, error
 	}
 
 	task.ID = id
+	task.Tags = splitTags(tags)
 
 	return task, true, nil
 }
 
 func (f *File) TaskCount(ctx context.Context) (int, error) {
-	const query = "SELECT count(*) FROM task"
+	const query = "SELECT count(*) FROM task WHERE ($1 IS NULL OR owner_id = $1)"
 
 	var count int
-	err := f.db.QueryRowContext(ctx, query).Scan(&count)
+	err := f.db.QueryRowContext(ctx, query, ownerArg(ctx)).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -109,47 +181,42 @@ func (f *File) TaskCount(ctx context.Context) (int, error) {
 }
 
 func (f *File) Tasks(ctx context.Context, query entity.TaskQuery) (entity.TaskPage, error) {
-	page := entity.TaskPage{Start: (query.Page - 1) * query.Size}
+	const countQuery = "SELECT count(*) FROM task WHERE ($1 IS NULL OR owner_id = $1)"
 
-	const resultsQuery = "SELECT count(*) FROM task WHERE subject LIKE $1"
-	const rowSelectQuery = "SELECT id, created_at, due_date, subject FROM task WHERE subject LIKE $1"
-	rowsQuery := fmt.Sprintf("%s ORDER BY %s LIMIT %d OFFSET %d", rowSelectQuery,
-		taskOrderClause(query.Sort, query.Order), query.Size, (query.Page-1)*query.Size)
-	subjectLike := likeArg(query.Filter)
+	page := entity.TaskPage{Start: query.Offset()}
 
-	tx, err := f.db.BeginTx(ctx, nil)
+	from, args := taskFilter(ctx, query)
+	resultsQuery := "SELECT count(*) FROM " + from
+	rowsQuery := fmt.Sprintf("SELECT id, created_at, due_date, subject, status, relevance, %s, description, %s FROM %s ORDER BY %s LIMIT %d OFFSET %d",
+		tagsColumn, dependencyColumns, from, taskOrderClause(query.Sort, query.Order), query.Limit(), query.Offset())
+
+	tx, err := f.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
 		return entity.TaskPage{}, err
 	}
+	defer rollback(tx)
 
-	defer func() {
-		err := tx.Rollback()
-		if err != nil {
-			log.Error("task list query read rollback failed", err)
-		}
-	}()
-
-	count, err := f.TaskCount(ctx)
+	err = tx.QueryRowContext(ctx, countQuery, ownerArg(ctx)).Scan(&page.Count)
 	if err != nil {
 		return page, err
 	}
-	page.Count = count
-
-	if page.Start > count {
-		return page, nil
-	}
 
-	var results int
-	err = f.db.QueryRowContext(ctx, resultsQuery, subjectLike).Scan(&results)
+	err = tx.QueryRowContext(ctx, resultsQuery, args...).Scan(&page.Results)
 	if err != nil {
 		return page, err
 	}
-	page.Results = results
 
-	rows, err := f.db.QueryContext(ctx, rowsQuery, subjectLike)
+	if page.Start >= page.Results {
+		page.Tasks = []entity.TaskOverview{}
+
+		return page, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, rowsQuery, args...)
 	if err != nil {
 		return page, err
 	}
+	defer rows.Close()
 
 	tasks, err := scanTaskOverviews(rows)
 	if err != nil {
@@ -161,9 +228,42 @@ func (f *File) Tasks(ctx context.Context, query entity.TaskQuery) (entity.TaskPa
 }
 
 func (f *File) UpdateTask(ctx context.Context, id uuid.UUID, data entity.TaskData) (entity.Task, bool, error) {
-	const query = "UPDATE task SET (due_date, subject, description) = ($2, $3, $4) WHERE id = $1"
+	const query = `UPDATE task SET (due_date, subject, description, recurrence, version) = ($2, $3, $4, $5, version + 1)
+		WHERE id = $1 AND $6 IN (0, version) AND ($7 IS NULL OR owner_id = $7)`
+
+	tags, err := entity.NormalizeTags(data.Tags)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+	defer rollback(tx)
+
+	result, err := tx.ExecContext(ctx, query, id, data.DueDate, data.Subject, data.Description, data.Recurrence, data.Version, ownerArg(ctx))
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return entity.Task{}, false, fmt.Errorf("rows access failed: %w", err)
+	}
+
+	if rows != 1 {
+		rollback(tx)
+
+		return f.conflict(ctx, id)
+	}
+
+	err = setTaskTags(ctx, tx, id, tags)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
 
-	_, err := f.db.ExecContext(ctx, query, id, data.DueDate, data.Subject, data.Description)
+	err = tx.Commit()
 	if err != nil {
 		return entity.Task{}, false, err
 	}
@@ -171,8 +271,251 @@ func (f *File) UpdateTask(ctx context.Context, id uuid.UUID, data entity.TaskDat
 	return f.Task(ctx, id)
 }
 
-func likeArg(arg string) string {
-	return "%" + strings.ReplaceAll(arg, "%", "") + "%"
+// conflict returns the current task as conflict of a failed update, not found if it was deleted.
+func (f *File) conflict(ctx context.Context, id uuid.UUID) (entity.Task, bool, error) {
+	task, ok, err := f.Task(ctx, id)
+	if err != nil || !ok {
+		return entity.Task{}, false, err
+	}
+
+	return entity.Task{}, true, entity.ConflictError{Task: task}
+}
+
+func (f *File) UpdateTaskStatus(ctx context.Context, id uuid.UUID, status entity.TaskStatus) (entity.Task, bool, error) {
+	query, args := statusUpdate(id, ownerArg(ctx), status, time.Now())
+
+	result, err := f.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return entity.Task{}, false, err
+	}
+
+	task, found, err := f.Task(ctx, id)
+	if err == nil && found && updated == 0 {
+		return entity.Task{}, true, entity.TransitionError{Task: task, Status: status}
+	}
+
+	return task, found, err
+}
+
+func (f *File) ExportTasks(ctx context.Context) iter.Seq2[entity.Task, error] {
+	const query = `WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0 FROM task WHERE parent_id IS NULL
+			UNION ALL
+			SELECT task.id, tree.depth + 1 FROM task JOIN tree ON task.parent_id = tree.id
+		)
+		SELECT task.id, created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, parent_id, recurrence, next_id, ` +
+		tagsColumn + ` FROM task JOIN tree ON task.id = tree.id WHERE ($1 IS NULL OR owner_id = $1) ORDER BY tree.depth, created_at, task.id`
+
+	return func(yield func(entity.Task, error) bool) {
+		rows, err := f.db.QueryContext(ctx, query, ownerArg(ctx))
+		if err != nil {
+			yield(entity.Task{}, err)
+
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var task entity.Task
+			var tags sql.NullString
+			err := rows.Scan(&task.ID, &task.CreatedAt, &task.DueDate, &task.Subject, &task.Description,
+				&task.Status, &task.StartedAt, &task.DoneAt, &task.CancelledAt, &task.Version, &task.ParentID, &task.Recurrence, &task.NextID, &tags)
+			if err != nil {
+				yield(task, err)
+
+				return
+			}
+			task.Tags = splitTags(tags)
+
+			if !yield(task, nil) {
+				return
+			}
+		}
+
+		err = rows.Err()
+		if err != nil {
+			yield(entity.Task{}, err)
+		}
+	}
+}
+
+func (f *File) ImportTask(ctx context.Context, task entity.Task) (bool, error) {
+	const (
+		ownerQuery  = "SELECT owner_id FROM task WHERE id = $1"
+		insertQuery = `INSERT INTO task (id, created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, owner_id, parent_id, recurrence, next_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+		updateQuery = `UPDATE task SET (created_at, due_date, subject, description, status, started_at, done_at, cancelled_at, version, parent_id, recurrence, next_id)
+			= ($2, $3, $4, $5, $6, $7, $8, $9, version + 1, $10, $11, $12) WHERE id = $1`
+	)
+
+	tags, err := entity.NormalizeTags(task.Tags)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer rollback(tx)
+
+	var owner uuid.NullUUID
+	err = tx.QueryRowContext(ctx, ownerQuery, task.ID).Scan(&owner)
+	created := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !created {
+		return false, err
+	}
+
+	if !created && !owns(ctx, owner) {
+		return false, fmt.Errorf("task %s of another user: %w", task.ID, entity.ErrNotFound)
+	}
+
+	err = checkParent(ctx, tx, task.ID, task.ParentID)
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		_, err = tx.ExecContext(ctx, insertQuery, task.ID, task.CreatedAt, task.DueDate, task.Subject, task.Description,
+			task.Status, task.StartedAt, task.DoneAt, task.CancelledAt, max(task.Version, 1), ownerArg(ctx), parentArg(task.ParentID), task.Recurrence,
+			nextArg(task.NextID))
+	} else {
+		_, err = tx.ExecContext(ctx, updateQuery, task.ID, task.CreatedAt, task.DueDate, task.Subject, task.Description,
+			task.Status, task.StartedAt, task.DoneAt, task.CancelledAt, parentArg(task.ParentID), task.Recurrence, nextArg(task.NextID))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = setTaskTags(ctx, tx, task.ID, tags)
+	if err != nil {
+		return false, err
+	}
+
+	return created, tx.Commit()
+}
+
+// statusUpdate returns the statement and its arguments to record the time of the status transition.
+// It updates only a task of a source state of the status.
+func statusUpdate(id uuid.UUID, owner any, status entity.TaskStatus, now time.Time) (string, []any) {
+	args := []any{id, owner, status}
+	sources := []string{}
+	for _, source := range status.Sources() {
+		args = append(args, source)
+		sources = append(sources, fmt.Sprintf("$%d", len(args)))
+	}
+	scope := " WHERE id = $1 AND ($2 IS NULL OR owner_id = $2) AND status IN (" + strings.Join(sources, ", ") + ")"
+	at := fmt.Sprintf("$%d", len(args)+1)
+
+	switch status {
+	case entity.TaskStatusInProgress:
+		return "UPDATE task SET (status, started_at, version) = ($3, " + at + ", version + 1)" + scope, append(args, now)
+	case entity.TaskStatusDone:
+		return "UPDATE task SET (status, done_at, version) = ($3, " + at + ", version + 1)" + scope, append(args, now)
+	case entity.TaskStatusCancelled:
+		return "UPDATE task SET (status, cancelled_at, version) = ($3, " + at + ", version + 1)" + scope, append(args, now)
+	}
+
+	return "UPDATE task SET (status, done_at, cancelled_at, version) = ($3, NULL, NULL, version + 1)" + scope, args
+}
+
+// taskFilter returns the ranked source and WHERE clause and its arguments to match the query.
+func taskFilter(ctx context.Context, query entity.TaskQuery) (string, []any) {
+	from := "task, (SELECT 0.0 AS relevance) AS search"
+	conditions := []string{}
+	args := []any{}
+
+	if terms := entity.SearchTerms(query.Search); len(terms) > 0 {
+		args = append(args, matchArg(terms))
+		from = `task JOIN (SELECT id AS task_id, -bm25(task_fts, 0.0, 4.0, 1.0) AS relevance
+			FROM task_fts WHERE task_fts MATCH $1) AS search ON search.task_id = task.id`
+	}
+
+	if user, ok := entity.UserFromContext(ctx); ok {
+		args = append(args, user.ID)
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+
+	if len(query.Statuses) > 0 {
+		placeholders := make([]string, len(query.Statuses))
+		for s, status := range query.Statuses {
+			args = append(args, status)
+			placeholders[s] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf("status IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if len(query.Tags) > 0 {
+		placeholders := make([]string, len(query.Tags))
+		for t, tag := range query.Tags {
+			args = append(args, tag)
+			placeholders[t] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf(`id IN (SELECT task_tag.task_id FROM task_tag
+			JOIN tag ON tag.id = task_tag.tag_id WHERE tag.name IN (%s)
+			GROUP BY task_tag.task_id HAVING count(*) = %d)`, strings.Join(placeholders, ", "), len(query.Tags)))
+	}
+
+	if query.Blocked {
+		conditions = append(conditions, `id IN (SELECT task_dependency.task_id FROM task_dependency
+			JOIN task AS blocker ON blocker.id = task_dependency.blocker_id WHERE blocker.status IN (0, 1))`)
+	}
+
+	if len(conditions) == 0 {
+		return from, args
+	}
+
+	return from + " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// matchArg returns a FTS5 query matching all terms as prefixes, the terms contain only letters and digits.
+func matchArg(terms []string) string {
+	phrases := make([]string, len(terms))
+	for t, term := range terms {
+		phrases[t] = `"` + term + `"*`
+	}
+
+	return strings.Join(phrases, " ")
+}
+
+// withPragmas enables the foreign key constraints and waits on locks for every connection of the pool,
+// write transactions lock immediately to wait for other writers instead of failing on the first write.
+func withPragmas(dsn string) string {
+	const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + pragmas
+	}
+
+	return dsn + "?" + pragmas
+}
+
+// ownerArg returns the ID of the context user to scope the tasks, NULL matches the tasks of all users.
+func ownerArg(ctx context.Context) any {
+	if user, ok := entity.UserFromContext(ctx); ok {
+		return user.ID
+	}
+
+	return nil
+}
+
+// owns reports whether the task owner is the context user, all owners without user.
+func owns(ctx context.Context, owner uuid.NullUUID) bool {
+	user, ok := entity.UserFromContext(ctx)
+
+	return !ok || (owner.Valid && owner.UUID == user.ID)
+}
+
+func rollback(tx *sql.Tx) {
+	err := tx.Rollback()
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Error("transaction rollback failed", err)
+	}
 }
 
 func taskSort(sort entity.TaskSort) string {
@@ -183,6 +526,8 @@ func taskSort(sort entity.TaskSort) string {
 		return "due_date"
 	case entity.TaskSortSubject:
 		return "subject"
+	case entity.TaskSortRelevance:
+		return "relevance"
 	}
 
 	return "id"
@@ -196,16 +541,20 @@ func toSQLOrder(order entity.SortOrder) string {
 	return "DESC"
 }
 
+// taskOrderClause orders by the sort column and the ID to get a stable paging.
 func taskOrderClause(sort entity.TaskSort, order entity.SortOrder) string {
-	return fmt.Sprintf("%s %s", taskSort(sort), toSQLOrder(order))
+	if sort == entity.TaskSortRelevance { // most relevant first in both orders
+		return "relevance DESC, id " + toSQLOrder(order)
+	}
+
+	return fmt.Sprintf("%s %s, id %s", taskSort(sort), toSQLOrder(order), toSQLOrder(order))
 }
 
 func scanTaskOverviews(rows *sql.Rows) ([]entity.TaskOverview, error) {
 	tasks := []entity.TaskOverview{}
 
 	for rows.Next() {
-		var task entity.TaskOverview
-		err := rows.Scan(&task.ID, &task.CreatedAt, &task.DueDate, &task.Subject)
+		task, err := scanTaskOverview(rows)
 		if err != nil {
 			return tasks, err
 		}
@@ -213,5 +562,23 @@ func scanTaskOverviews(rows *sql.Rows) ([]entity.TaskOverview, error) {
 		tasks = append(tasks, task)
 	}
 
-	return tasks, nil
+	return tasks, rows.Err()
+}
+
+// scanTaskOverview scans the overview columns, the tags, the description of the checklist and the dependency columns
+// followed by the extra columns.
+func scanTaskOverview(rows *sql.Rows, extra ...any) (entity.TaskOverview, error) {
+	var task entity.TaskOverview
+	var tags sql.NullString
+	var description string
+	dest := append([]any{&task.ID, &task.CreatedAt, &task.DueDate, &task.Subject, &task.Status, &task.Relevance, &tags, &description,
+		&task.Blockers, &task.Blocking}, extra...)
+	err := rows.Scan(dest...)
+	if err != nil {
+		return task, err
+	}
+	task.Tags = splitTags(tags)
+	task.Checklist = entity.ParseChecklist(description)
+
+	return task, nil
 }
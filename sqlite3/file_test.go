@@ -0,0 +1,17 @@
+package sqlite3_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/entity/storagetest"
+	"github.com/dgf/go-ssr-x/sqlite3"
+)
+
+func TestFile(t *testing.T) {
+	storagetest.Run(t, func(ctx context.Context) (entity.Storage, error) {
+		return sqlite3.NewFile(ctx, filepath.Join(t.TempDir(), "tasks.sqlite"))
+	})
+}
@@ -0,0 +1,145 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+// recurringTask is a task with a recurrence and the owner of its next occurrence.
+type recurringTask struct {
+	entity.Task
+
+	owner uuid.NullUUID
+}
+
+// RecurTasks passes the recurrences on in one transaction.
+func (f *File) RecurTasks(ctx context.Context, now time.Time) ([]entity.Occurrence, error) {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(tx)
+
+	tasks, err := recurringTasks(ctx, tx, uuid.NullUUID{})
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := []entity.Occurrence{}
+	for _, task := range tasks {
+		occurrence, recurred, err := recur(ctx, tx, task, now)
+		if err != nil {
+			return nil, err
+		}
+
+		if recurred {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	return occurrences, tx.Commit()
+}
+
+func (f *File) RecurTask(ctx context.Context, id uuid.UUID, now time.Time) (entity.Occurrence, bool, error) {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entity.Occurrence{}, false, err
+	}
+	defer rollback(tx)
+
+	tasks, err := recurringTasks(ctx, tx, uuid.NullUUID{UUID: id, Valid: true})
+	if err != nil || len(tasks) == 0 {
+		return entity.Occurrence{}, false, err
+	}
+
+	occurrence, recurred, err := recur(ctx, tx, tasks[0], now)
+	if err != nil || !recurred {
+		return entity.Occurrence{}, false, err
+	}
+
+	return occurrence, true, tx.Commit()
+}
+
+// recur links the next occurrence to the task or ends its recurrence by the task version, a concurrent run passes the
+// recurrence on only once.
+func recur(ctx context.Context, tx *sql.Tx, task recurringTask, now time.Time) (entity.Occurrence, bool, error) {
+	const (
+		passQuery   = "UPDATE task SET (recurrence, next_id, version) = ($3, $4, version + 1) WHERE id = $1 AND version = $2"
+		insertQuery = `INSERT INTO task (id, due_date, subject, description, owner_id, parent_id, recurrence)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	)
+
+	if !task.Recurs(now) {
+		return entity.Occurrence{}, false, nil
+	}
+
+	occurrence := entity.Occurrence{ID: task.ID, OwnerID: task.owner.UUID}
+	data, ok := task.NextOccurrence(now)
+	recurrence := entity.Recurrence{} // ended
+	if ok {
+		occurrence.NextID = uuid.New()
+		recurrence = task.Recurrence
+	}
+
+	result, err := tx.ExecContext(ctx, passQuery, task.ID, task.Version, recurrence, nextArg(occurrence.NextID))
+	if err != nil {
+		return entity.Occurrence{}, false, err
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows != 1 {
+		return entity.Occurrence{}, false, nil // passed on by a concurrent run
+	}
+
+	if ok {
+		_, err = tx.ExecContext(ctx, insertQuery, occurrence.NextID, data.DueDate, data.Subject, data.Description,
+			task.owner, parentArg(data.ParentID), data.Recurrence)
+		if err != nil {
+			return entity.Occurrence{}, false, err
+		}
+
+		err = setTaskTags(ctx, tx, occurrence.NextID, data.Tags)
+		if err != nil {
+			return entity.Occurrence{}, false, err
+		}
+	}
+
+	return occurrence, true, nil
+}
+
+// recurringTasks returns the tasks with a recurrence that is not passed on, only the task of the ID if valid.
+func recurringTasks(ctx context.Context, tx *sql.Tx, id uuid.NullUUID) ([]recurringTask, error) {
+	const query = `SELECT id, created_at, due_date, subject, description, status, version, parent_id, recurrence, next_id, owner_id, ` +
+		tagsColumn + ` FROM task WHERE recurrence <> '' AND next_id IS NULL AND ($1 IS NULL OR owner_id = $1) AND ($2 IS NULL OR id = $2)
+		ORDER BY created_at, id`
+
+	rows, err := tx.QueryContext(ctx, query, ownerArg(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []recurringTask{}
+	for rows.Next() {
+		var task recurringTask
+		var tags sql.NullString
+		err := rows.Scan(&task.ID, &task.CreatedAt, &task.DueDate, &task.Subject, &task.Description, &task.Status,
+			&task.Version, &task.ParentID, &task.Recurrence, &task.NextID, &task.owner, &tags)
+		if err != nil {
+			return nil, err
+		}
+
+		task.Tags = splitTags(tags)
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// nextArg returns the next occurrence ID, NULL if the recurrence isn't passed on.
+func nextArg(nextID uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: nextID, Valid: nextID != uuid.Nil}
+}
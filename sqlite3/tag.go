@@ -0,0 +1,181 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+func (f *File) AddTag(ctx context.Context, name string) (entity.Tag, error) {
+	tag, err := entity.NormalizeTag(name)
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	id, err := ensureTag(ctx, f.db, ownerArg(ctx), tag)
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	return entity.Tag{ID: id, Name: tag}, nil
+}
+
+func (f *File) Tags(ctx context.Context) ([]entity.Tag, error) {
+	const query = "SELECT id, name FROM tag WHERE $1 IS NULL OR owner_id = $1 ORDER BY name"
+
+	rows, err := f.db.QueryContext(ctx, query, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []entity.Tag{}
+	for rows.Next() {
+		var tag entity.Tag
+		err := rows.Scan(&tag.ID, &tag.Name)
+		if err != nil {
+			return tags, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (f *File) RenameTag(ctx context.Context, id uuid.UUID, name string) (entity.Tag, bool, error) {
+	const (
+		ownerQuery  = "SELECT owner_id FROM tag WHERE id = $1 AND ($2 IS NULL OR owner_id = $2)"
+		existsQuery = "SELECT id FROM tag WHERE name = $1 AND owner_id IS $2"
+		updateQuery = "UPDATE tag SET name = $2 WHERE id = $1"
+	)
+
+	tag, err := entity.NormalizeTag(name)
+	if err != nil {
+		return entity.Tag{}, false, err
+	}
+
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entity.Tag{}, false, err
+	}
+	defer rollback(tx)
+
+	var owner uuid.NullUUID
+	err = tx.QueryRowContext(ctx, ownerQuery, id, ownerArg(ctx)).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Tag{}, false, nil
+	} else if err != nil {
+		return entity.Tag{}, false, err
+	}
+
+	var existing uuid.UUID
+	err = tx.QueryRowContext(ctx, existsQuery, tag, owner).Scan(&existing)
+	if err == nil && existing != id {
+		return entity.Tag{}, false, fmt.Errorf("%w: %s", entity.ErrTagExists, tag)
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return entity.Tag{}, false, err
+	}
+
+	result, err := tx.ExecContext(ctx, updateQuery, id, tag)
+	if err != nil {
+		return entity.Tag{}, false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return entity.Tag{}, false, fmt.Errorf("rows access failed: %w", err)
+	}
+
+	if rows != 1 {
+		return entity.Tag{}, false, nil
+	}
+
+	return entity.Tag{ID: id, Name: tag}, true, tx.Commit()
+}
+
+func (f *File) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	const query = "DELETE FROM tag WHERE id = $1 AND ($2 IS NULL OR owner_id = $2)"
+
+	result, err := f.db.ExecContext(ctx, query, id, ownerArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows access failed: %w", err)
+	}
+
+	if rows != 1 {
+		return fmt.Errorf("no tag for %s: %w", id, entity.ErrNotFound)
+	}
+
+	return nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ensureTag returns the ID of the named tag of the owner, it creates missing tags.
+func ensureTag(ctx context.Context, q querier, owner any, name string) (uuid.UUID, error) {
+	const query = `INSERT INTO tag (id, name, owner_id) VALUES ($1, $2, $3)
+		ON CONFLICT (coalesce(owner_id, ''), name) DO UPDATE SET name = excluded.name RETURNING id`
+
+	var id uuid.UUID
+	err := q.QueryRowContext(ctx, query, uuid.New(), name, owner).Scan(&id)
+
+	return id, err
+}
+
+// setTaskTags replaces the tags of the task with the named tags of the task owner.
+func setTaskTags(ctx context.Context, tx *sql.Tx, id uuid.UUID, tags []string) error {
+	const (
+		ownerQuery  = "SELECT owner_id FROM task WHERE id = $1"
+		deleteQuery = "DELETE FROM task_tag WHERE task_id = $1"
+		insertQuery = "INSERT INTO task_tag (task_id, tag_id) VALUES ($1, $2)"
+	)
+
+	var owner uuid.NullUUID
+	err := tx.QueryRowContext(ctx, ownerQuery, id).Scan(&owner)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		tagID, err := ensureTag(ctx, tx, owner, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, insertQuery, id, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func splitTags(tags sql.NullString) []string {
+	if !tags.Valid || tags.String == "" {
+		return []string{}
+	}
+
+	names := strings.Split(tags.String, ",")
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,134 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+func (f *File) Subtasks(ctx context.Context, id uuid.UUID) ([]entity.Subtask, error) {
+	const query = `WITH RECURSIVE subtask(id, parent_id, depth) AS (
+			SELECT id, parent_id, 1 FROM task WHERE parent_id = $1
+			UNION ALL
+			SELECT task.id, task.parent_id, subtask.depth + 1 FROM task JOIN subtask ON task.parent_id = subtask.id
+		)
+		SELECT task.id, created_at, due_date, subject, status, 0.0, ` + tagsColumn + `, description, ` + dependencyColumns + `, subtask.parent_id, subtask.depth
+		FROM subtask JOIN task ON task.id = subtask.id WHERE ($2 IS NULL OR owner_id = $2)`
+
+	rows, err := f.db.QueryContext(ctx, query, id, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subtasks := []entity.Subtask{}
+	for rows.Next() {
+		var subtask entity.Subtask
+		subtask.TaskOverview, err = scanTaskOverview(rows, &subtask.ParentID, &subtask.Depth)
+		if err != nil {
+			return subtasks, err
+		}
+
+		subtasks = append(subtasks, subtask)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return subtasks, err
+	}
+
+	return entity.SortSubtasks(id, subtasks), nil
+}
+
+func (f *File) Ancestors(ctx context.Context, id uuid.UUID) ([]entity.TaskOverview, error) {
+	const query = `WITH RECURSIVE ancestor(id, depth) AS (
+			SELECT parent_id, 1 FROM task WHERE id = $1 AND parent_id IS NOT NULL
+			UNION ALL
+			SELECT task.parent_id, ancestor.depth + 1 FROM task JOIN ancestor ON task.id = ancestor.id WHERE task.parent_id IS NOT NULL
+		)
+		SELECT task.id, created_at, due_date, subject, status, 0.0, ` + tagsColumn + `, description, ` + dependencyColumns + `
+		FROM ancestor JOIN task ON task.id = ancestor.id WHERE ($2 IS NULL OR owner_id = $2) ORDER BY ancestor.depth`
+
+	rows, err := f.db.QueryContext(ctx, query, id, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTaskOverviews(rows)
+}
+
+func (f *File) DeleteTaskTree(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	const query = `WITH RECURSIVE tree(id) AS (
+			SELECT id FROM task WHERE id = $1 AND ($2 IS NULL OR owner_id = $2)
+			UNION ALL
+			SELECT task.id FROM task JOIN tree ON task.parent_id = tree.id
+		)
+		DELETE FROM task WHERE id IN (SELECT id FROM tree) RETURNING id`
+
+	rows, err := f.db.QueryContext(ctx, query, id, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var deleted uuid.UUID
+		err := rows.Scan(&deleted)
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, deleted)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return ids, err
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no row for %s: %w", id, entity.ErrNotFound)
+	}
+
+	return ids, nil
+}
+
+// checkParent returns an ErrInvalidParent error if the parent is missing or a subtask of the task, a task without parent is valid.
+func checkParent(ctx context.Context, tx *sql.Tx, id, parentID uuid.UUID) error {
+	const query = `WITH RECURSIVE ancestor(id) AS (
+			SELECT id FROM task WHERE id = $1 AND ($2 IS NULL OR owner_id = $2)
+			UNION
+			SELECT task.parent_id FROM task JOIN ancestor ON task.id = ancestor.id WHERE task.parent_id IS NOT NULL
+		)
+		SELECT count(*), count(*) FILTER (WHERE id = $3) FROM ancestor`
+
+	if parentID == uuid.Nil {
+		return nil
+	}
+
+	var ancestors, cycles int
+	err := tx.QueryRowContext(ctx, query, parentID, ownerArg(ctx), id).Scan(&ancestors, &cycles)
+	if err != nil {
+		return err
+	}
+
+	if ancestors == 0 {
+		return fmt.Errorf("parent %s: %w", parentID, entity.ErrInvalidParent)
+	}
+
+	if cycles > 0 {
+		return fmt.Errorf("parent %s is a subtask of %s: %w", parentID, id, entity.ErrInvalidParent)
+	}
+
+	return nil
+}
+
+// parentArg returns the parent ID, NULL of a top-level task.
+func parentArg(parentID uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: parentID, Valid: parentID != uuid.Nil}
+}
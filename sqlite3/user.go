@@ -0,0 +1,111 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+func (f *File) AddUser(ctx context.Context, name, password string) (entity.User, error) {
+	const query = `INSERT INTO users (id, name, password_hash, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO NOTHING`
+
+	normalized, err := entity.NormalizeUserName(name)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	hash, err := entity.HashPassword(password)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user := entity.User{ID: uuid.New(), Name: normalized, PasswordHash: hash, CreatedAt: time.Now()}
+	result, err := f.db.ExecContext(ctx, query, user.ID, user.Name, user.PasswordHash, user.CreatedAt)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return entity.User{}, fmt.Errorf("rows access failed: %w", err)
+	}
+
+	if rows != 1 {
+		return entity.User{}, fmt.Errorf("%w: %s", entity.ErrUserExists, normalized)
+	}
+
+	return user, nil
+}
+
+func (f *File) User(ctx context.Context, id uuid.UUID) (entity.User, bool, error) {
+	const query = "SELECT id, name, password_hash, created_at FROM users WHERE id = $1"
+
+	return scanUser(f.db.QueryRowContext(ctx, query, id))
+}
+
+func (f *File) UserByName(ctx context.Context, name string) (entity.User, bool, error) {
+	const query = "SELECT id, name, password_hash, created_at FROM users WHERE name = $1"
+
+	normalized, err := entity.NormalizeUserName(name)
+	if err != nil {
+		return entity.User{}, false, nil // an invalid name is unknown
+	}
+
+	return scanUser(f.db.QueryRowContext(ctx, query, normalized))
+}
+
+// ClaimTasks passes the tasks without user to the user, their tags merge by name into the tags of the user.
+func (f *File) ClaimTasks(ctx context.Context, userID uuid.UUID) (int, error) {
+	const (
+		claimQuery = "UPDATE task SET owner_id = $1 WHERE owner_id IS NULL"
+		mergeQuery = `UPDATE task_tag SET tag_id = owned.id FROM tag, tag AS owned
+			WHERE tag.id = task_tag.tag_id AND tag.owner_id IS NULL AND owned.name = tag.name AND owned.owner_id = $1`
+		deleteQuery = "DELETE FROM tag WHERE owner_id IS NULL AND name IN (SELECT name FROM tag WHERE owner_id = $1)"
+		tagQuery    = "UPDATE tag SET owner_id = $1 WHERE owner_id IS NULL"
+	)
+
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rollback(tx)
+
+	result, err := tx.ExecContext(ctx, claimQuery, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows access failed: %w", err)
+	}
+
+	for _, query := range []string{mergeQuery, deleteQuery, tagQuery} {
+		_, err = tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int(rows), tx.Commit()
+}
+
+func scanUser(row *sql.Row) (entity.User, bool, error) {
+	var user entity.User
+	err := row.Scan(&user.ID, &user.Name, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, false, nil
+		}
+
+		return entity.User{}, false, err
+	}
+
+	return user, true, nil
+}
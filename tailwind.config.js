@@ -1,6 +1,6 @@
 /** @type {import('tailwindcss').Config} */
 module.exports = {
-  content: ["./web/**/*.templ"],
+  content: ["./web/**/*.templ", "./web/view/*.go"],
   theme: {
     container: {
       center: true,
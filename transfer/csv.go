@@ -0,0 +1,249 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+// csvColumns are the header of the CSV format, an import matches the columns by name and requires the subject only.
+var csvColumns = []string{"id", "createdAt", "dueDate", "subject", "description", "status", "startedAt", "doneAt", "cancelledAt", "tags", "version", "parentId", "recurrence", "nextId"}
+
+type csvEncoder struct {
+	writer *csv.Writer
+	header bool
+}
+
+type csvDecoder struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{writer: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Encode(task entity.Task) error {
+	if !e.header {
+		e.header = true
+		err := e.writer.Write(csvColumns)
+		if err != nil {
+			return err
+		}
+	}
+
+	record := newRecord(task)
+
+	return e.writer.Write([]string{
+		record.ID.String(),
+		record.CreatedAt.Format(time.RFC3339Nano),
+		record.DueDate,
+		record.Subject,
+		record.Description,
+		record.Status,
+		csvTime(record.StartedAt),
+		csvTime(record.DoneAt),
+		csvTime(record.CancelledAt),
+		strings.Join(record.Tags, ","),
+		strconv.FormatInt(record.Version, 10),
+		csvID(record.ParentID),
+		record.Recurrence,
+		csvID(record.NextID),
+	})
+}
+
+// Close writes the header of an empty export and flushes the rows.
+func (e *csvEncoder) Close() error {
+	if !e.header {
+		e.header = true
+		err := e.writer.Write(csvColumns)
+		if err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+func csvID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+func newCSVDecoder(r io.Reader) *csvDecoder {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	return &csvDecoder{reader: reader}
+}
+
+func (d *csvDecoder) Decode() (entity.Task, error) {
+	if d.columns == nil {
+		err := d.readHeader()
+		if err != nil {
+			return entity.Task{}, err
+		}
+	}
+
+	row, err := d.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+		return entity.Task{}, &RowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()}
+	}
+	if err != nil {
+		return entity.Task{}, err
+	}
+
+	line, _ := d.reader.FieldPos(0)
+
+	record, err := d.record(row)
+	if err != nil {
+		return entity.Task{}, &RowError{Row: line, ID: d.value(row, "id"), Message: err.Error()}
+	}
+
+	return rowTask(line, "", record)
+}
+
+func (d *csvDecoder) readHeader() error {
+	header, err := d.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	d.columns = make(map[string]int, len(header))
+	for c, name := range header {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(csvColumns, name) {
+			return fmt.Errorf("unknown CSV column %q, use %s", name, strings.Join(csvColumns, ", "))
+		}
+		d.columns[name] = c
+	}
+
+	if _, ok := d.columns["subject"]; !ok {
+		return errors.New("missing CSV column subject")
+	}
+
+	return nil
+}
+
+func (d *csvDecoder) value(row []string, column string) string {
+	c, ok := d.columns[column]
+	if !ok {
+		return ""
+	}
+
+	return row[c]
+}
+
+func (d *csvDecoder) record(row []string) (Record, error) {
+	record := Record{
+		DueDate:     d.value(row, "dueDate"),
+		Subject:     d.value(row, "subject"),
+		Description: d.value(row, "description"),
+		Status:      d.value(row, "status"),
+		Recurrence:  d.value(row, "recurrence"),
+	}
+
+	var errs []string
+	if id := d.value(row, "id"); id != "" {
+		var err error
+		record.ID, err = uuid.Parse(id)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid ID %q", id))
+		}
+	}
+
+	for _, r := range []struct {
+		column, name string
+		target       **uuid.UUID
+	}{
+		{"parentId", "parent ID", &record.ParentID},
+		{"nextId", "next ID", &record.NextID},
+	} {
+		if value := d.value(row, r.column); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("invalid %s %q", r.name, value))
+			} else {
+				*r.target = &id
+			}
+		}
+	}
+
+	createdAt, err := parseTime(d.value(row, "createdAt"))
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	record.CreatedAt = createdAt
+
+	for _, t := range []struct {
+		column string
+		target **time.Time
+	}{
+		{"startedAt", &record.StartedAt},
+		{"doneAt", &record.DoneAt},
+		{"cancelledAt", &record.CancelledAt},
+	} {
+		at, err := parseTime(d.value(row, t.column))
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		if !at.IsZero() {
+			*t.target = &at
+		}
+	}
+
+	if tags := d.value(row, "tags"); strings.TrimSpace(tags) != "" {
+		record.Tags = strings.Split(tags, ",")
+	}
+
+	if version := d.value(row, "version"); version != "" {
+		record.Version, err = strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid version %q", version))
+		}
+	}
+
+	if len(errs) > 0 {
+		return record, errors.New(strings.Join(errs, "; "))
+	}
+
+	return record, nil
+}
+
+// parseTime returns the zero time of an empty value.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, use RFC 3339", value)
+	}
+
+	return t, nil
+}
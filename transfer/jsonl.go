@@ -0,0 +1,66 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/dgf/go-ssr-x/entity"
+)
+
+// maxLineSize limits a JSON line, e.g. of a long description.
+const maxLineSize = 4 << 20
+
+type jsonlEncoder struct {
+	encoder *json.Encoder
+}
+
+type jsonlDecoder struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLEncoder(w io.Writer) *jsonlEncoder {
+	return &jsonlEncoder{encoder: json.NewEncoder(w)}
+}
+
+func (e *jsonlEncoder) Encode(task entity.Task) error {
+	return e.encoder.Encode(newRecord(task))
+}
+
+func (e *jsonlEncoder) Close() error {
+	return nil
+}
+
+func newJSONLDecoder(r io.Reader) *jsonlDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	return &jsonlDecoder{scanner: scanner}
+}
+
+func (d *jsonlDecoder) Decode() (entity.Task, error) {
+	for d.scanner.Scan() {
+		d.line++
+		line := bytes.TrimSpace(d.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record Record
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return entity.Task{}, &RowError{Row: d.line, Message: err.Error()}
+		}
+
+		return rowTask(d.line, "", record)
+	}
+
+	err := d.scanner.Err()
+	if err != nil {
+		return entity.Task{}, err
+	}
+
+	return entity.Task{}, io.EOF
+}
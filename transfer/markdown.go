@@ -0,0 +1,216 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	markdownExt          = ".md"
+	frontMatterDelimiter = "---\n"
+)
+
+// maxFileSize limits a Markdown file.
+const maxFileSize = maxLineSize
+
+type markdownDirEncoder struct {
+	dir string
+}
+
+type markdownDirDecoder struct {
+	dir   string
+	names []string
+	file  int
+}
+
+type tarEncoder struct {
+	writer *tar.Writer
+}
+
+type tarDecoder struct {
+	reader *tar.Reader
+	file   int
+}
+
+// NewMarkdownDirEncoder writes a Markdown file per task named by its ID into the directory, it creates a missing directory.
+func NewMarkdownDirEncoder(dir string) (Encoder, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &markdownDirEncoder{dir: dir}, nil
+}
+
+// NewMarkdownDirDecoder reads the Markdown files of the directory ordered by name.
+func NewMarkdownDirDecoder(dir string) (Decoder, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), markdownExt) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return &markdownDirDecoder{dir: dir, names: names}, nil
+}
+
+func (e *markdownDirEncoder) Encode(task entity.Task) error {
+	content, err := markdownFile(task)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(e.dir, task.ID.String()+markdownExt), content, 0o600)
+}
+
+func (e *markdownDirEncoder) Close() error {
+	return nil
+}
+
+func (d *markdownDirDecoder) Decode() (entity.Task, error) {
+	if d.file == len(d.names) {
+		return entity.Task{}, io.EOF
+	}
+	name := d.names[d.file]
+	d.file++
+
+	content, err := os.ReadFile(filepath.Join(d.dir, name))
+	if err != nil {
+		return entity.Task{}, err
+	}
+
+	return markdownTask(d.file, name, content)
+}
+
+func newTarEncoder(w io.Writer) *tarEncoder {
+	return &tarEncoder{writer: tar.NewWriter(w)}
+}
+
+func (e *tarEncoder) Encode(task entity.Task) error {
+	content, err := markdownFile(task)
+	if err != nil {
+		return err
+	}
+
+	err = e.writer.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     task.ID.String() + markdownExt,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		ModTime:  task.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = e.writer.Write(content)
+
+	return err
+}
+
+// Close writes the end of the archive.
+func (e *tarEncoder) Close() error {
+	return e.writer.Close()
+}
+
+func newTarDecoder(r io.Reader) *tarDecoder {
+	return &tarDecoder{reader: tar.NewReader(r)}
+}
+
+func (d *tarDecoder) Decode() (entity.Task, error) {
+	for {
+		header, err := d.reader.Next()
+		if err != nil {
+			return entity.Task{}, err
+		}
+
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, markdownExt) {
+			continue
+		}
+		d.file++
+		name := path.Base(header.Name)
+
+		if header.Size > maxFileSize {
+			return entity.Task{}, &RowError{Row: d.file, Name: name, Message: fmt.Sprintf("file exceeds %d bytes", maxFileSize)}
+		}
+
+		content, err := io.ReadAll(d.reader)
+		if err != nil {
+			return entity.Task{}, err
+		}
+
+		return markdownTask(d.file, name, content)
+	}
+}
+
+// markdownFile returns the description with a YAML front matter of the other fields.
+func markdownFile(task entity.Task) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(frontMatterDelimiter)
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	err := encoder.Encode(newRecord(task))
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString(frontMatterDelimiter + "\n")
+	b.WriteString(task.Description + "\n")
+
+	return b.Bytes(), nil
+}
+
+func markdownTask(file int, name string, content []byte) (entity.Task, error) {
+	record, err := parseMarkdownFile(string(content))
+	if err != nil {
+		return entity.Task{}, &RowError{Row: file, Name: name, Message: err.Error()}
+	}
+
+	return rowTask(file, name, record)
+}
+
+func parseMarkdownFile(content string) (Record, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	header, ok := strings.CutPrefix(content, frontMatterDelimiter)
+	if !ok {
+		return Record{}, errors.New("missing front matter")
+	}
+
+	header, body, ok := strings.Cut(header, "\n"+frontMatterDelimiter)
+	if !ok {
+		header, ok = strings.CutSuffix(header, "\n---") // without description
+		if !ok {
+			return Record{}, errors.New("missing end of front matter")
+		}
+	}
+
+	var record Record
+	decoder := yaml.NewDecoder(strings.NewReader(header))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&record)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return record, fmt.Errorf("invalid front matter: %w", err)
+	}
+	record.Description = strings.Trim(body, "\n")
+
+	return record, nil
+}
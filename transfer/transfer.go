@@ -0,0 +1,390 @@
+// Package transfer exports and imports complete tasks as JSON lines, CSV or Markdown files with a front matter.
+package transfer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/google/uuid"
+)
+
+const (
+	FormatJSONL    = "jsonl"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown" // a directory or a tar archive of Markdown files
+)
+
+// Formats are the supported transfer formats.
+var Formats = []string{FormatJSONL, FormatCSV, FormatMarkdown}
+
+// ErrInvalidInput aborts an import that can't be read, e.g. a broken archive or an unknown CSV column.
+var ErrInvalidInput = errors.New("invalid import input")
+
+// Record is the transferred structure of a task, it uses the field names of the JSON API.
+type Record struct {
+	ID          uuid.UUID  `json:"id"                    yaml:"id"` // nil imports a new task
+	CreatedAt   time.Time  `json:"createdAt"             yaml:"createdAt"`
+	DueDate     string     `json:"dueDate"               yaml:"dueDate"` // YYYY-MM-DD, empty without due date
+	Subject     string     `json:"subject"               yaml:"subject"`
+	Description string     `json:"description"           yaml:"-"`      // the content of a Markdown file
+	Status      string     `json:"status"                yaml:"status"` // open, in-progress, done or cancelled
+	StartedAt   *time.Time `json:"startedAt,omitempty"   yaml:"startedAt,omitempty"`
+	DoneAt      *time.Time `json:"doneAt,omitempty"      yaml:"doneAt,omitempty"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty" yaml:"cancelledAt,omitempty"`
+	Tags        []string   `json:"tags"                  yaml:"tags"`
+	Version     int64      `json:"version"               yaml:"version"`
+	ParentID    *uuid.UUID `json:"parentId,omitempty"    yaml:"parentId,omitempty"`   // of a subtask
+	Recurrence  string     `json:"recurrence,omitempty"  yaml:"recurrence,omitempty"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO
+	NextID      *uuid.UUID `json:"nextId,omitempty"      yaml:"nextId,omitempty"`     // of a passed on recurrence
+}
+
+// Encoder writes the tasks of an export.
+type Encoder interface {
+	Encode(task entity.Task) error
+	// Close writes the buffered tasks, it doesn't close the underlying writer.
+	Close() error
+}
+
+// Decoder reads the tasks of an import.
+type Decoder interface {
+	// Decode returns the next task, a *RowError for an invalid row and io.EOF after the last row.
+	Decode() (entity.Task, error)
+}
+
+// postponedTask is a subtask of a missing parent with the row and the error of the last attempt.
+type postponedTask struct {
+	row  int
+	task entity.Task
+	err  error
+}
+
+// RowError reports an invalid row of an import.
+type RowError struct {
+	Row     int    `json:"row"`            // line of JSON lines and CSV, file number of Markdown
+	Name    string `json:"name,omitempty"` // file name of Markdown
+	ID      string `json:"id,omitempty"`
+	Message string `json:"error"`
+}
+
+// Report summarizes an import.
+type Report struct {
+	DryRun  bool       `json:"dryRun"`
+	Rows    int        `json:"rows"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+func (e *RowError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("row %d (%s): %s", e.Row, e.Name, e.Message)
+	}
+
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// NewEncoder returns an encoder of the JSON lines, CSV or Markdown (tar archive) format.
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLEncoder(w), nil
+	case FormatCSV:
+		return newCSVEncoder(w), nil
+	case FormatMarkdown:
+		return newTarEncoder(w), nil
+	}
+
+	return nil, unknownFormat(format)
+}
+
+// NewDecoder returns a decoder of the JSON lines, CSV or Markdown (tar archive) format.
+func NewDecoder(format string, r io.Reader) (Decoder, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLDecoder(r), nil
+	case FormatCSV:
+		return newCSVDecoder(r), nil
+	case FormatMarkdown:
+		return newTarDecoder(r), nil
+	}
+
+	return nil, unknownFormat(format)
+}
+
+func unknownFormat(format string) error {
+	return fmt.Errorf("unknown transfer format %q, use %s", format, strings.Join(Formats, ", "))
+}
+
+// Export encodes all tasks of the storage and returns their number.
+func Export(ctx context.Context, storage entity.Storage, encoder Encoder) (int, error) {
+	count := 0
+	for task, err := range storage.ExportTasks(ctx) {
+		if err != nil {
+			return count, err
+		}
+
+		err = encoder.Encode(task)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, encoder.Close()
+}
+
+// Import upserts the decoded tasks by ID, it skips and reports the invalid rows. A subtask of a missing parent is retried
+// after the last row, e.g. of Markdown files ordered by name. A dry run only counts the changes, it checks that the
+// parents exist but not the cycles of the hierarchy.
+func Import(ctx context.Context, storage entity.Storage, decoder Decoder, dryRun bool) (Report, error) {
+	report := Report{DryRun: dryRun, Errors: []RowError{}}
+	imported := map[uuid.UUID]bool{}
+	var postponed []postponedTask
+	for {
+		task, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.Rows++
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			report.fail(*rowErr)
+
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+
+		created, err := importTask(ctx, storage, task, dryRun, imported)
+		if errors.Is(err, entity.ErrInvalidParent) {
+			postponed = append(postponed, postponedTask{row: report.Rows, task: task, err: err})
+
+			continue
+		}
+
+		err = report.count(report.Rows, task, created, err, imported)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, importPostponed(ctx, storage, postponed, &report, imported)
+}
+
+// importPostponed retries the subtasks until no parent is imported anymore, the remaining ones fail.
+func importPostponed(ctx context.Context, storage entity.Storage, postponed []postponedTask, report *Report, imported map[uuid.UUID]bool) error {
+	for len(postponed) > 0 {
+		var missing []postponedTask
+		for _, p := range postponed {
+			created, err := importTask(ctx, storage, p.task, report.DryRun, imported)
+			if errors.Is(err, entity.ErrInvalidParent) {
+				missing = append(missing, postponedTask{row: p.row, task: p.task, err: err})
+
+				continue
+			}
+
+			err = report.count(p.row, p.task, created, err, imported)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(missing) == len(postponed) {
+			for _, p := range missing {
+				report.fail(RowError{Row: p.row, ID: p.task.ID.String(), Message: p.err.Error()})
+			}
+			slices.SortStableFunc(report.Errors, func(a, b RowError) int { return cmp.Compare(a.Row, b.Row) })
+
+			return nil
+		}
+		postponed = missing
+	}
+
+	return nil
+}
+
+// importTask saves the task, a dry run checks the parent unless it was imported before.
+func importTask(ctx context.Context, storage entity.Storage, task entity.Task, dryRun bool, imported map[uuid.UUID]bool) (bool, error) {
+	if !dryRun {
+		return storage.ImportTask(ctx, task)
+	}
+
+	if task.ParentID != uuid.Nil && !imported[task.ParentID] {
+		_, found, err := storage.Task(ctx, task.ParentID)
+		if err != nil {
+			return false, err
+		}
+
+		if !found {
+			return false, fmt.Errorf("parent %s: %w", task.ParentID, entity.ErrInvalidParent)
+		}
+	}
+
+	_, found, err := storage.Task(ctx, task.ID)
+
+	return !found, err
+}
+
+// count adds the imported task or the row error to the report, it returns the other errors.
+func (r *Report) count(row int, task entity.Task, created bool, err error, imported map[uuid.UUID]bool) error {
+	switch {
+	case errors.Is(err, entity.ErrNotFound), errors.Is(err, entity.ErrInvalidTag):
+		r.fail(RowError{Row: row, ID: task.ID.String(), Message: err.Error()})
+	case err != nil:
+		return fmt.Errorf("import of task %s failed: %w", task.ID, err)
+	case created:
+		r.Created++
+		imported[task.ID] = true
+	default:
+		r.Updated++
+		imported[task.ID] = true
+	}
+
+	return nil
+}
+
+func (r *Report) fail(err RowError) {
+	r.Failed++
+	r.Errors = append(r.Errors, err)
+}
+
+func newRecord(task entity.Task) Record {
+	dueDate := ""
+	if !task.DueDate.IsZero() {
+		dueDate = task.DueDate.Format(time.DateOnly)
+	}
+
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var parentID *uuid.UUID
+	if task.ParentID != uuid.Nil {
+		parentID = &task.ParentID
+	}
+
+	var nextID *uuid.UUID
+	if task.NextID != uuid.Nil {
+		nextID = &task.NextID
+	}
+
+	return Record{
+		ID:          task.ID,
+		CreatedAt:   task.CreatedAt,
+		DueDate:     dueDate,
+		Subject:     task.Subject,
+		Description: task.Description,
+		Status:      task.Status.String(),
+		StartedAt:   task.StartedAt,
+		DoneAt:      task.DoneAt,
+		CancelledAt: task.CancelledAt,
+		Tags:        tags,
+		Version:     task.Version,
+		ParentID:    parentID,
+		Recurrence:  task.Recurrence.String(),
+		NextID:      nextID,
+	}
+}
+
+// task validates the record, a nil ID is a new task and a zero creation time is now.
+func (r Record) task() (entity.Task, error) {
+	if length := utf8.RuneCountInString(r.Subject); length < entity.SubjectMinLength || length > entity.SubjectMaxLength {
+		return entity.Task{}, fmt.Errorf("invalid subject %q, requires %d to %d characters", r.Subject, entity.SubjectMinLength, entity.SubjectMaxLength)
+	}
+
+	var dueDate time.Time
+	if r.DueDate != "" {
+		var err error
+		dueDate, err = time.Parse(time.DateOnly, r.DueDate)
+		if err != nil {
+			return entity.Task{}, fmt.Errorf("invalid due date %q, use YYYY-MM-DD", r.DueDate)
+		}
+	}
+
+	status := entity.TaskStatusDefault
+	if r.Status != "" {
+		var ok bool
+		status, ok = entity.ParseTaskStatus(r.Status)
+		if !ok {
+			return entity.Task{}, fmt.Errorf("invalid status %q", r.Status)
+		}
+	}
+
+	tags, err := entity.NormalizeTags(r.Tags)
+	if err != nil {
+		return entity.Task{}, err
+	}
+
+	if r.Version < 0 {
+		return entity.Task{}, fmt.Errorf("invalid version %d", r.Version)
+	}
+
+	id := r.ID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+
+	createdAt := r.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	parentID := uuid.Nil
+	if r.ParentID != nil {
+		parentID = *r.ParentID
+	}
+
+	recurrence, err := entity.ParseRecurrence(r.Recurrence)
+	if err != nil {
+		return entity.Task{}, fmt.Errorf("invalid recurrence %q: %w", r.Recurrence, err)
+	}
+
+	nextID := uuid.Nil
+	if r.NextID != nil {
+		nextID = *r.NextID
+	}
+
+	return entity.Task{
+		ID:          id,
+		CreatedAt:   createdAt,
+		DueDate:     dueDate,
+		Subject:     r.Subject,
+		Description: r.Description,
+		Status:      status,
+		StartedAt:   r.StartedAt,
+		DoneAt:      r.DoneAt,
+		CancelledAt: r.CancelledAt,
+		Tags:        tags,
+		Version:     r.Version,
+		ParentID:    parentID,
+		Recurrence:  recurrence,
+		NextID:      nextID,
+	}, nil
+}
+
+// rowTask returns the task of the record, a *RowError if it is invalid.
+func rowTask(row int, name string, record Record) (entity.Task, error) {
+	task, err := record.task()
+	if err != nil {
+		rowErr := &RowError{Row: row, Name: name, Message: err.Error()}
+		if record.ID != uuid.Nil {
+			rowErr.ID = record.ID.String()
+		}
+
+		return task, rowErr
+	}
+
+	return task, nil
+}
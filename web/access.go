@@ -0,0 +1,82 @@
+package web
+
+import (
+	"cmp"
+	"net/http"
+	"time"
+
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	requestIDMaxLength = 128
+)
+
+// accessRecorder records the status and the size of a response.
+type accessRecorder struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int
+}
+
+func (a *accessRecorder) WriteHeader(status int) {
+	if a.status == 0 {
+		a.status = status
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *accessRecorder) Write(b []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	n, err := a.ResponseWriter.Write(b)
+	a.bytes += n
+
+	return n, err
+}
+
+// Unwrap lets a http.ResponseController access the flushing of the event streams.
+func (a *accessRecorder) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
+
+// requestID returns the propagated request ID header, a new ID if it is missing or invalid.
+func requestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if id == "" || len(id) > requestIDMaxLength {
+		return uuid.NewString()
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' { // printable ASCII only, e.g. no line breaks
+			return uuid.NewString()
+		}
+	}
+
+	return id
+}
+
+// accessLogging assigns the request ID to the context and the response, and logs every handled request.
+func accessLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := requestID(r)
+		w.Header().Set(requestIDHeader, id)
+
+		recorder := &accessRecorder{ResponseWriter: w}
+		r = r.WithContext(log.With(r.Context(), "request_id", id))
+		next.ServeHTTP(recorder, r)
+
+		log.InfoContext(r.Context(), "request handled",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"pattern", r.Pattern,
+			"status", cmp.Or(recorder.status, http.StatusOK),
+			"bytes", recorder.bytes,
+			"duration", time.Since(start))
+	})
+}
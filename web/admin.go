@@ -0,0 +1,47 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/dgf/go-ssr-x/log"
+)
+
+type apiLogLevel struct {
+	Level string `json:"level"`
+}
+
+// admin registers the handler for requests with the admin token as bearer authorization.
+func (s *Server) admin(pattern string, handler apiHandlerFunc) {
+	s.handle(pattern, jsonHandler(func(w http.ResponseWriter, r *http.Request) (int, any) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+
+			return apiError(r, http.StatusUnauthorized, "unauthorized", nil)
+		}
+
+		return handler(w, r)
+	}))
+}
+
+func (s *Server) APILogLevel(_ http.ResponseWriter, _ *http.Request) (int, any) {
+	return http.StatusOK, apiLogLevel{Level: log.Level()}
+}
+
+func (s *Server) APIUpdateLogLevel(w http.ResponseWriter, r *http.Request) (int, any) {
+	var body apiLogLevel
+	err := decodeAPIBody(w, r, &body)
+	if err != nil {
+		return apiBadRequest(r, err)
+	}
+
+	err = log.SetLevel(body.Level)
+	if err != nil {
+		return apiBadRequest(r, fieldError{field: "level", value: body.Level})
+	}
+	log.InfoContext(r.Context(), "log level changed", "level", log.Level())
+
+	return http.StatusOK, apiLogLevel{Level: log.Level()}
+}
@@ -0,0 +1,607 @@
+package web
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/locale"
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/google/uuid"
+)
+
+const (
+	apiPrefix       = "/api/v1"
+	apiMaxBodyBytes = 1 << 20
+)
+
+type apiHandlerFunc func(w http.ResponseWriter, r *http.Request) (int, any)
+
+// problem is a RFC 9457 problem details body, the code is the client error message ID.
+type problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail"`
+	Instance string            `json:"instance"`
+	Code     string            `json:"code"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+type apiTaskData struct {
+	DueDate     string     `json:"dueDate"`
+	Subject     string     `json:"subject"`
+	Description string     `json:"description"`
+	Tags        []string   `json:"tags"`
+	ParentID    *uuid.UUID `json:"parentId,omitempty"`   // of a new subtask, an update keeps the parent
+	Recurrence  string     `json:"recurrence,omitempty"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO
+}
+
+type apiTaskStatus struct {
+	Status string `json:"status"`
+}
+
+type apiTask struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DueDate     string     `json:"dueDate"`
+	Subject     string     `json:"subject"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	DoneAt      *time.Time `json:"doneAt,omitempty"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
+	Tags        []string   `json:"tags"`
+	Version     int64      `json:"version"`
+	ParentID    *uuid.UUID `json:"parentId,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	NextID      *uuid.UUID `json:"nextId,omitempty"` // of a passed on recurrence
+}
+
+type apiTaskOverview struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	DueDate   string    `json:"dueDate"`
+	Subject   string    `json:"subject"`
+	Status    string    `json:"status"`
+	Tags      []string  `json:"tags"`
+	Relevance float64   `json:"relevance,omitempty"`
+	Blockers  int       `json:"blockers,omitempty"` // unfinished tasks blocking this one
+	Blocking  int       `json:"blocking,omitempty"` // unfinished tasks blocked by this one
+}
+
+type apiTaskDependencies struct {
+	BlockedBy []apiTaskOverview `json:"blockedBy"`
+	Blocking  []apiTaskOverview `json:"blocking"`
+}
+
+type apiWorkPlan struct {
+	Tasks []apiTaskOverview `json:"tasks"`
+}
+
+type apiPageMeta struct {
+	Page    int `json:"page"`
+	Size    int `json:"size"`
+	Pages   int `json:"pages"`
+	Start   int `json:"start"`
+	Count   int `json:"count"`
+	Results int `json:"results"`
+}
+
+type apiPageLinks struct {
+	Self string `json:"self"`
+	Prev string `json:"prev,omitempty"`
+	Next string `json:"next,omitempty"`
+}
+
+type apiTaskPage struct {
+	Meta  apiPageMeta       `json:"meta"`
+	Links apiPageLinks      `json:"links"`
+	Tasks []apiTaskOverview `json:"tasks"`
+}
+
+func (s *Server) api(pattern string, handler apiHandlerFunc) {
+	s.handle(pattern, jsonHandler(func(w http.ResponseWriter, r *http.Request) (int, any) {
+		user, ok := s.apiUser(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="tasks", charset="UTF-8"`)
+
+			return apiError(r, http.StatusUnauthorized, "unauthorized", nil)
+		}
+
+		return handler(w, r.WithContext(userContext(r.Context(), user)))
+	}))
+}
+
+// jsonHandler writes the status and the JSON body of the handler, a problem as application/problem+json.
+// A zero status means the handler wrote the response, e.g. a stream.
+func jsonHandler(handler apiHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+
+		ctx := localeContext(r)
+		status, body := handler(w, r.WithContext(ctx))
+		if status == 0 {
+			return
+		}
+
+		if _, ok := body.(problem); ok {
+			w.Header().Set("Content-Type", "application/problem+json")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+
+		w.WriteHeader(status)
+		if body == nil {
+			return
+		}
+
+		err := json.NewEncoder(w).Encode(body)
+		if err != nil {
+			log.ErrorContext(ctx, "JSON encoding failed", err)
+		}
+	}
+}
+
+func apiError(r *http.Request, statusCode int, messageID string, data map[string]string) (int, any) {
+	return statusCode, problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   locale.TranslateData(r.Context(), messageID, data),
+		Instance: r.URL.Path,
+		Code:     messageID,
+		Data:     data,
+	}
+}
+
+func (ts *TaskServer) APITasks(_ http.ResponseWriter, r *http.Request) (int, any) {
+	query := queryParams2TaskQuery(r.URL.Query())
+
+	page, err := ts.storage.Tasks(r.Context(), query)
+	if err != nil {
+		log.ErrorContext(r.Context(), "API tasks access failed", err)
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	return http.StatusOK, taskPage2API(query, page)
+}
+
+func (ts *TaskServer) APITask(w http.ResponseWriter, r *http.Request) (int, any) {
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		setETag(w, task)
+
+		return http.StatusOK, task2API(task)
+	})
+}
+
+func (ts *TaskServer) APICreateTask(w http.ResponseWriter, r *http.Request) (int, any) {
+	data, err := decodeAPITaskData(w, r)
+	if err != nil {
+		return apiBadRequest(r, err)
+	}
+
+	id, err := ts.storage.AddTask(r.Context(), data)
+	if errors.Is(err, entity.ErrInvalidParent) {
+		return apiBadRequest(r, fieldError{field: "parentId", value: data.ParentID.String()})
+	} else if err != nil {
+		log.ErrorContext(r.Context(), "API task creation failed", err)
+
+		return apiError(r, http.StatusInternalServerError, "database_error", map[string]string{"message": err.Error()})
+	}
+
+	task, ok, err := ts.storage.Task(r.Context(), id)
+	if err != nil || !ok {
+		log.ErrorContext(r.Context(), "API created task access failed", errors.Join(err, fmt.Errorf("task %s found %t", id, ok)))
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	w.Header().Set("Location", apiPrefix+"/tasks/"+id.String())
+	setETag(w, task)
+
+	return http.StatusCreated, task2API(task)
+}
+
+func (ts *TaskServer) APIUpdateTask(w http.ResponseWriter, r *http.Request) (int, any) {
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		headerData := map[string]string{"header": "If-Match", "value": r.Header.Get("If-Match")}
+
+		return apiError(r, http.StatusBadRequest, "bad_request_header", headerData)
+	}
+
+	data, err := decodeAPITaskData(w, r)
+	if err != nil {
+		return apiBadRequest(r, err)
+	}
+	data.Version = version
+
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		updated, ok, err := ts.storage.UpdateTask(r.Context(), task.ID, data)
+		var conflict entity.ConflictError
+		if errors.As(err, &conflict) {
+			return apiError(r, http.StatusPreconditionFailed, "conflict_task_update", conflictData(conflict.Task, data))
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("API task update failed: %v", err))
+
+			return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+		if !ok { // e.g. delete while updating
+			return apiError(r, http.StatusConflict, "conflict_task_update", nil)
+		}
+
+		setETag(w, updated)
+
+		return http.StatusOK, task2API(updated)
+	})
+}
+
+func (ts *TaskServer) APIUpdateTaskStatus(w http.ResponseWriter, r *http.Request) (int, any) {
+	var body apiTaskStatus
+	err := decodeAPIBody(w, r, &body)
+	if err != nil {
+		return apiBadRequest(r, err)
+	}
+
+	status, ok := entity.ParseTaskStatus(body.Status)
+	if !ok {
+		return apiBadRequest(r, fieldError{field: "status", value: body.Status})
+	}
+
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		updated, ok, err := ts.storage.UpdateTaskStatus(r.Context(), task.ID, status)
+		if ok && err == nil {
+			updated, err = ts.passRecurrence(r.Context(), updated)
+		}
+		var transition entity.TransitionError
+		if errors.As(err, &transition) {
+			statusData := map[string]string{"from": transition.Task.Status.String(), "to": status.String()}
+
+			return apiError(r, http.StatusConflict, "conflict_task_status", statusData)
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("API task status update failed: %v", err))
+
+			return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+		if !ok {
+			return apiError(r, http.StatusConflict, "conflict_task_update", nil)
+		}
+
+		setETag(w, updated)
+
+		return http.StatusOK, task2API(updated)
+	})
+}
+
+// APIDeleteTask moves the subtasks to the parent, the cascade query param deletes them.
+func (ts *TaskServer) APIDeleteTask(_ http.ResponseWriter, r *http.Request) (int, any) {
+	cascadeParam := r.URL.Query().Get("cascade")
+	cascade, err := strconv.ParseBool(cmp.Or(cascadeParam, "false"))
+	if err != nil {
+		return apiError(r, http.StatusBadRequest, "bad_request_query_param", map[string]string{"param": "cascade", "value": cascadeParam})
+	}
+
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		var err error
+		if cascade {
+			_, err = ts.storage.DeleteTaskTree(r.Context(), task.ID)
+		} else {
+			err = ts.storage.DeleteTask(r.Context(), task.ID)
+		}
+		if errors.Is(err, entity.ErrNotFound) {
+			return apiError(r, http.StatusNotFound, "not_found_task", map[string]string{"id": task.ID.String()})
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("API task deletion failed: %v", err))
+
+			return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return http.StatusNoContent, nil
+	})
+}
+
+// APIWorkPlan lists the unfinished tasks topologically ordered, the blockers first.
+func (ts *TaskServer) APIWorkPlan(_ http.ResponseWriter, r *http.Request) (int, any) {
+	plan, err := ts.storage.WorkPlan(r.Context())
+	if err != nil {
+		log.ErrorContext(r.Context(), "API work plan access failed", err)
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	return http.StatusOK, apiWorkPlan{Tasks: overviews2API(plan)}
+}
+
+func (ts *TaskServer) APITaskDependencies(_ http.ResponseWriter, r *http.Request) (int, any) {
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		dependencies, err := ts.storage.Dependencies(r.Context(), task.ID)
+		if err != nil {
+			log.ErrorContext(r.Context(), "API task dependencies access failed", err)
+
+			return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return http.StatusOK, apiTaskDependencies{
+			BlockedBy: overviews2API(dependencies.BlockedBy),
+			Blocking:  overviews2API(dependencies.Blocking),
+		}
+	})
+}
+
+// APIAddTaskDependency blocks the task by the blocker, an existing dependency is kept.
+func (ts *TaskServer) APIAddTaskDependency(_ http.ResponseWriter, r *http.Request) (int, any) {
+	pBlocker := r.PathValue("blockerId")
+	blockerID, err := uuid.Parse(pBlocker)
+	if err != nil {
+		return apiError(r, http.StatusBadRequest, "bad_request_path_param", map[string]string{"param": "blockerId", "value": pBlocker})
+	}
+
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		err := ts.storage.AddDependency(r.Context(), task.ID, blockerID)
+		if errors.Is(err, entity.ErrDependencyCycle) {
+			return apiError(r, http.StatusConflict, "conflict_task_dependency", map[string]string{"id": task.ID.String(), "blocker": pBlocker})
+		} else if errors.Is(err, entity.ErrNotFound) {
+			return apiError(r, http.StatusNotFound, "not_found_task", map[string]string{"id": pBlocker})
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("API task dependency creation failed: %v", err))
+
+			return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return http.StatusNoContent, nil
+	})
+}
+
+func (ts *TaskServer) APIRemoveTaskDependency(_ http.ResponseWriter, r *http.Request) (int, any) {
+	pBlocker := r.PathValue("blockerId")
+	blockerID, err := uuid.Parse(pBlocker)
+	if err != nil {
+		return apiError(r, http.StatusBadRequest, "bad_request_path_param", map[string]string{"param": "blockerId", "value": pBlocker})
+	}
+
+	return ts.apiHandleTask(r, func(task entity.Task) (int, any) {
+		err := ts.storage.RemoveDependency(r.Context(), task.ID, blockerID)
+		if errors.Is(err, entity.ErrNotFound) {
+			return apiError(r, http.StatusNotFound, "not_found_task_dependency", map[string]string{"id": task.ID.String(), "blocker": pBlocker})
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("API task dependency deletion failed: %v", err))
+
+			return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return http.StatusNoContent, nil
+	})
+}
+
+func (ts *TaskServer) apiHandleTask(r *http.Request, handler func(entity.Task) (int, any)) (int, any) {
+	pid := r.PathValue("id")
+	id, err := uuid.Parse(pid)
+	if err != nil {
+		return apiError(r, http.StatusBadRequest, "bad_request_path_param", map[string]string{"param": "id", "value": pid})
+	}
+
+	task, ok, err := ts.storage.Task(r.Context(), id)
+	if err != nil {
+		log.ErrorContext(r.Context(), "API task access failed", err)
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+	if !ok {
+		return apiError(r, http.StatusNotFound, "not_found_task", map[string]string{"id": pid})
+	}
+
+	return handler(task)
+}
+
+// setETag sets the task version as entity tag, it's expected by If-Match on updates.
+func setETag(w http.ResponseWriter, task entity.Task) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(task.Version, 10)))
+}
+
+// ifMatchVersion returns the version of the If-Match entity tag, zero if unset or any. A weak tag matches too, e.g. of
+// a proxy that compressed the response.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	tag := r.Header.Get("If-Match")
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Unquote(strings.TrimPrefix(tag, "W/"))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(version, 10, 64)
+}
+
+// conflictData returns the current version and the names of the fields that differ from the update data.
+func conflictData(task entity.Task, data entity.TaskData) map[string]string {
+	fields := []string{}
+	if task.Subject != data.Subject {
+		fields = append(fields, "subject")
+	}
+	if !task.DueDate.Equal(data.DueDate) {
+		fields = append(fields, "dueDate")
+	}
+	if task.Description != data.Description {
+		fields = append(fields, "description")
+	}
+	if !slices.Equal(task.Tags, data.Tags) {
+		fields = append(fields, "tags")
+	}
+	if task.Recurrence.String() != data.Recurrence.String() {
+		fields = append(fields, "recurrence")
+	}
+
+	return map[string]string{
+		"version": strconv.FormatInt(task.Version, 10),
+		"fields":  strings.Join(fields, ","),
+	}
+}
+
+func apiBadRequest(r *http.Request, err error) (int, any) {
+	var fe fieldError
+	if errors.As(err, &fe) {
+		return apiError(r, http.StatusBadRequest, "bad_request_field", map[string]string{"field": fe.field, "value": fe.value})
+	}
+
+	return apiError(r, http.StatusBadRequest, "bad_request_body", map[string]string{"message": err.Error()})
+}
+
+func decodeAPIBody(w http.ResponseWriter, r *http.Request, body any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes)).Decode(body)
+}
+
+func decodeAPITaskData(w http.ResponseWriter, r *http.Request) (entity.TaskData, error) {
+	var body apiTaskData
+	err := decodeAPIBody(w, r, &body)
+	if err != nil {
+		return entity.TaskData{}, err
+	}
+
+	if length := utf8.RuneCountInString(body.Subject); length < entity.SubjectMinLength || length > entity.SubjectMaxLength {
+		return entity.TaskData{}, fieldError{field: "subject", value: body.Subject}
+	}
+
+	dueDate := time.Time{}
+	if body.DueDate != "" {
+		dueDate, err = time.Parse(time.DateOnly, body.DueDate)
+		if err != nil {
+			return entity.TaskData{}, fieldError{field: "dueDate", value: body.DueDate}
+		}
+	}
+
+	tags, err := entity.NormalizeTags(body.Tags)
+	if err != nil {
+		return entity.TaskData{}, fieldError{field: "tags", value: err.Error()}
+	}
+
+	parentID := uuid.Nil
+	if body.ParentID != nil {
+		parentID = *body.ParentID
+	}
+
+	recurrence, err := entity.ParseRecurrence(body.Recurrence)
+	if err != nil {
+		return entity.TaskData{}, fieldError{field: "recurrence", value: err.Error()}
+	}
+
+	return entity.TaskData{
+		DueDate:     dueDate,
+		Subject:     body.Subject,
+		Description: body.Description,
+		Tags:        tags,
+		ParentID:    parentID,
+		Recurrence:  recurrence,
+	}, nil
+}
+
+func apiDate(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+
+	return d.Format(time.DateOnly)
+}
+
+func task2API(task entity.Task) apiTask {
+	return apiTask{
+		ID:          task.ID,
+		CreatedAt:   task.CreatedAt,
+		DueDate:     apiDate(task.DueDate),
+		Subject:     task.Subject,
+		Description: task.Description,
+		Status:      task.Status.String(),
+		StartedAt:   task.StartedAt,
+		DoneAt:      task.DoneAt,
+		CancelledAt: task.CancelledAt,
+		Tags:        tagsOrEmpty(task.Tags),
+		Version:     task.Version,
+		ParentID:    idOrNil(task.ParentID),
+		Recurrence:  task.Recurrence.String(),
+		NextID:      idOrNil(task.NextID),
+	}
+}
+
+// tagsOrEmpty returns an empty slice without tags, it encodes as an empty array instead of null.
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// idOrNil returns nil without ID to omit it.
+func idOrNil(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return &id
+}
+
+func taskPage2API(query entity.TaskQuery, page entity.TaskPage) apiTaskPage {
+	size := query.Limit()
+	number := page.Start/size + 1
+	pages := int(math.Ceil(float64(page.Results) / float64(size)))
+
+	pageURL := func(number int) string {
+		query.Page = number
+		query.Size = size
+
+		return (&url.URL{Path: apiPrefix + "/tasks", RawQuery: taskQuery2QueryParams(query)}).String()
+	}
+
+	links := apiPageLinks{Self: pageURL(number)}
+	if number > 1 {
+		links.Prev = pageURL(min(number-1, max(pages, 1)))
+	}
+	if number < pages {
+		links.Next = pageURL(number + 1)
+	}
+
+	return apiTaskPage{
+		Meta: apiPageMeta{
+			Page:    number,
+			Size:    size,
+			Pages:   pages,
+			Start:   page.Start,
+			Count:   page.Count,
+			Results: page.Results,
+		},
+		Links: links,
+		Tasks: overviews2API(page.Tasks),
+	}
+}
+
+func overviews2API(overviews []entity.TaskOverview) []apiTaskOverview {
+	tasks := make([]apiTaskOverview, len(overviews))
+	for t, task := range overviews {
+		tasks[t] = apiTaskOverview{
+			ID:        task.ID,
+			CreatedAt: task.CreatedAt,
+			DueDate:   apiDate(task.DueDate),
+			Subject:   task.Subject,
+			Status:    task.Status.String(),
+			Tags:      tagsOrEmpty(task.Tags),
+			Relevance: task.Relevance,
+			Blockers:  task.Blockers,
+			Blocking:  task.Blocking,
+		}
+	}
+
+	return tasks
+}
@@ -0,0 +1,243 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/web"
+)
+
+const (
+	userName     = "alice"
+	userPassword = "alice password"
+)
+
+type taskPage struct {
+	Meta struct {
+		Page, Size, Pages, Start, Count, Results int
+	}
+	Links struct {
+		Self, Prev, Next string
+	}
+	Tasks []struct {
+		Subject string
+		Tags    json.RawMessage
+	}
+}
+
+type problem struct {
+	Status int
+	Code   string
+}
+
+func TestAPITasksPage(t *testing.T) {
+	baseURL, storage, ctx := startServer(t)
+	for _, subject := range []string{"task 5", "task 2", "task 4", "task 1", "task 3"} {
+		_, err := storage.AddTask(ctx, entity.TaskData{Subject: subject})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, body := request(t, http.MethodGet, baseURL+"/api/v1/tasks?page=2&size=2&sort=subject", "", "")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	var page taskPage
+	err := json.Unmarshal(body, &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := page.Meta
+	if meta.Page != 2 || meta.Size != 2 || meta.Pages != 3 || meta.Start != 2 || meta.Count != 5 || meta.Results != 5 {
+		t.Errorf("meta %+v, want page 2 of 3 with size 2 from 2 of 5 results", meta)
+	}
+
+	if !strings.Contains(page.Links.Prev, "page=1") || !strings.Contains(page.Links.Next, "page=3") {
+		t.Errorf("links %+v, want the pages 1 and 3", page.Links)
+	}
+
+	if len(page.Tasks) != 2 || page.Tasks[0].Subject != "task 3" || page.Tasks[1].Subject != "task 4" {
+		t.Fatalf("tasks %+v, want task 3 and task 4", page.Tasks)
+	}
+
+	if string(page.Tasks[0].Tags) != "[]" {
+		t.Errorf("tags %s, want []", page.Tasks[0].Tags)
+	}
+}
+
+func TestAPICreateTask(t *testing.T) {
+	baseURL, _, _ := startServer(t)
+
+	res, body := request(t, http.MethodPost, baseURL+"/api/v1/tasks", "", `{"subject":"new task","dueDate":"2026-11-01"}`)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("status %d, want %d: %s", res.StatusCode, http.StatusCreated, body)
+	}
+
+	location := res.Header.Get("Location")
+	if !strings.HasPrefix(location, "/api/v1/tasks/") || res.Header.Get("ETag") != `"1"` {
+		t.Errorf("location %q and ETag %q, want the task URL and version 1", location, res.Header.Get("ETag"))
+	}
+
+	var created map[string]any
+	err := json.Unmarshal(body, &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tags, ok := created["tags"].([]any); !ok || len(tags) != 0 {
+		t.Errorf("tags %v, want an empty array", created["tags"])
+	}
+
+	res, found := request(t, http.MethodGet, baseURL+location, "", "")
+	if res.StatusCode != http.StatusOK || string(found) != string(body) {
+		t.Errorf("status %d with task %s, want %d with %s", res.StatusCode, found, http.StatusOK, body)
+	}
+}
+
+func TestAPITaskRequests(t *testing.T) {
+	baseURL, storage, ctx := startServer(t)
+	tasksURL := baseURL + "/api/v1/tasks"
+	ids := make([]string, 2)
+	for i := range ids {
+		id, err := storage.AddTask(ctx, entity.TaskData{Subject: "task"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id.String()
+	}
+	first, second := tasksURL+"/"+ids[0], tasksURL+"/"+ids[1]
+
+	for _, c := range []struct {
+		name, method, url, ifMatch, body string
+		status                           int
+		code                             string // of a problem
+		etag                             string
+	}{
+		{"invalid subject", http.MethodPost, tasksURL, "", `{"subject":""}`, http.StatusBadRequest, "bad_request_field", ""},
+		{"weak version", http.MethodPut, first, `W/"1"`, `{"subject":"changed"}`, http.StatusOK, "", `"2"`},
+		{"outdated version", http.MethodPut, first, `"1"`, `{"subject":"again"}`, http.StatusPreconditionFailed, "conflict_task_update", ""},
+		{"invalid version", http.MethodPut, first, "2", `{"subject":"again"}`, http.StatusBadRequest, "bad_request_header", ""},
+		{"any version", http.MethodPut, first, "*", `{"subject":"again"}`, http.StatusOK, "", `"3"`},
+		{"block", http.MethodPut, first + "/dependencies/" + ids[1], "", "", http.StatusNoContent, "", ""},
+		{"block cycle", http.MethodPut, second + "/dependencies/" + ids[0], "", "", http.StatusConflict, "conflict_task_dependency", ""},
+		{"delete", http.MethodDelete, second, "", "", http.StatusNoContent, "", ""},
+		{"deleted", http.MethodGet, second, "", "", http.StatusNotFound, "not_found_task", ""},
+		{"delete deleted", http.MethodDelete, second, "", "", http.StatusNotFound, "not_found_task", ""},
+		{"unknown path", http.MethodGet, baseURL + "/api/v1/unknown", "", "", http.StatusNotFound, "not_found_path", ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			res, body := request(t, c.method, c.url, c.ifMatch, c.body)
+			if res.StatusCode != c.status {
+				t.Fatalf("status %d, want %d: %s", res.StatusCode, c.status, body)
+			}
+
+			if c.etag != "" && res.Header.Get("ETag") != c.etag {
+				t.Errorf("ETag %s, want %s", res.Header.Get("ETag"), c.etag)
+			}
+
+			if c.code == "" {
+				return
+			}
+
+			if contentType := res.Header.Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("content type %q, want application/problem+json", contentType)
+			}
+
+			var p problem
+			err := json.Unmarshal(body, &p)
+			if err != nil || p.Status != c.status || p.Code != c.code {
+				t.Errorf("problem %+v with error %v, want status %d and code %s", p, err, c.status, c.code)
+			}
+		})
+	}
+}
+
+// startServer serves the memory storage with a user until the test ends, it returns the URL, the storage and a context
+// of the user.
+func startServer(t *testing.T) (string, entity.Storage, context.Context) {
+	t.Helper()
+
+	storage := entity.NewMemory()
+	user, err := storage.AddUser(t.Context(), userName, userPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := freeAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := web.NewServer()
+	server.Addr = addr
+	server.Storage = storage
+	server.SessionKey = []byte("test session key")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		err := <-served
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	for !server.Ready() {
+		select {
+		case err := <-served:
+			t.Fatalf("serving failed: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	return "http://" + addr, storage, entity.WithUser(t.Context(), user)
+}
+
+// request sends the request of the user and returns the response with its read body.
+func request(t *testing.T, method, url, ifMatch, body string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(userName, userPassword)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	read, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res, read
+}
+
+func freeAddr() (string, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+
+	return listener.Addr().String(), nil
+}
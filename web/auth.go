@@ -0,0 +1,101 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/dgf/go-ssr-x/web/view"
+)
+
+func (s *Server) LoginForm(_ http.ResponseWriter, _ *http.Request) templ.Component {
+	return view.LoginForm("", "")
+}
+
+func (s *Server) Login(w http.ResponseWriter, r *http.Request) templ.Component {
+	name := r.PostFormValue("name")
+
+	user, _, err := s.storage.UserByName(r.Context(), name)
+	if err != nil {
+		log.ErrorContext(r.Context(), "user access failed", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return view.LoginForm(name, "internal_server_error")
+	}
+
+	if !user.CheckPassword(r.PostFormValue("password")) {
+		log.InfoContext(r.Context(), "login failed", "name", name)
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return view.LoginForm(name, "unauthorized_login")
+	}
+
+	http.SetCookie(w, s.sessions.cookie(r, user.ID, time.Now()))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+
+	return nil
+}
+
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) templ.Component {
+	http.SetCookie(w, s.sessions.expiredCookie(r))
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+	return nil
+}
+
+// sessionUser returns the existing user of the session cookie.
+func (s *Server) sessionUser(r *http.Request) (entity.User, bool) {
+	id, ok := s.sessions.userID(r, time.Now())
+	if !ok {
+		return entity.User{}, false
+	}
+
+	user, found, err := s.storage.User(r.Context(), id)
+	if err != nil {
+		log.ErrorContext(r.Context(), "session user access failed", err)
+
+		return entity.User{}, false
+	}
+
+	return user, found
+}
+
+// apiUser returns the user of the session cookie or of the basic authorization header.
+func (s *Server) apiUser(r *http.Request) (entity.User, bool) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return s.sessionUser(r)
+	}
+
+	user, _, err := s.storage.UserByName(r.Context(), name)
+	if err != nil {
+		log.ErrorContext(r.Context(), "API user access failed", err)
+
+		return entity.User{}, false
+	}
+
+	return user, user.CheckPassword(password)
+}
+
+// requireUser calls the handler with the session user in the context, it redirects anonymous requests to the login.
+func (s *Server) requireUser(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.sessionUser(r)
+		if !ok {
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", "/login")
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+			return
+		}
+
+		handler(w, r.WithContext(userContext(r.Context(), user)))
+	}
+}
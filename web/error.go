@@ -1,12 +1,23 @@
 package web
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/a-h/templ"
+	"github.com/dgf/go-ssr-x/entity"
 	"github.com/dgf/go-ssr-x/web/view"
 )
 
+type fieldError struct {
+	field string
+	value string
+}
+
+func (e fieldError) Error() string {
+	return fmt.Sprintf("invalid field %s value %q", e.field, e.value)
+}
+
 func clientError(w http.ResponseWriter, r *http.Request, statusCode int, messageID string, data map[string]string) templ.Component {
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Add("HX-Reswap", "afterbegin")
@@ -19,3 +30,17 @@ func clientError(w http.ResponseWriter, r *http.Request, statusCode int, message
 
 	return view.ClientError(messageID, data)
 }
+
+// taskConflict shows the changes of the current task that conflict with the update data.
+func taskConflict(w http.ResponseWriter, r *http.Request, task entity.Task, data entity.TaskData) templ.Component {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Add("HX-Reswap", "afterbegin")
+		w.WriteHeader(http.StatusConflict)
+
+		return view.TaskConflictNotify(task, data)
+	}
+
+	w.WriteHeader(http.StatusConflict)
+
+	return view.TaskConflict(task, data)
+}
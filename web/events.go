@@ -0,0 +1,119 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/dgf/go-ssr-x/web/view"
+)
+
+// taskEventsKeepAlive is shorter than the usual proxy timeouts to keep idle streams open.
+const taskEventsKeepAlive = 25 * time.Second
+
+// TaskEvents streams the task changes as server-sent events that swap the task rows out of band.
+func (ts *TaskServer) TaskEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := localeContext(r)
+	rc := http.NewResponseController(w)
+
+	err := rc.SetWriteDeadline(time.Time{}) // the stream outlasts the server write timeout
+	if err != nil {
+		log.ErrorContext(ctx, "task events write deadline reset failed", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	user, _ := entity.UserFromContext(ctx)
+	events, unsubscribe := ts.hub.Subscribe(user.ID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(taskEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for err == nil {
+		err = rc.Flush()
+		if err != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			err = ts.writeTaskEvent(ctx, w, event)
+		}
+	}
+
+	log.InfoContext(ctx, "task events stream closed", "error", err.Error())
+}
+
+func (ts *TaskServer) writeTaskEvent(ctx context.Context, w io.Writer, event entity.TaskEvent) error {
+	component, err := ts.taskEventComponent(ctx, event)
+	if err != nil {
+		log.ErrorContext(ctx, "task event access failed", err)
+
+		return nil
+	}
+	if component == nil { // not visible to the user
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err = component.Render(ctx, &buf)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: task\ndata: %s\n\n", strings.ReplaceAll(buf.String(), "\n", "\ndata: "))
+
+	return err
+}
+
+// taskEventComponent swaps the row of an updated or deleted task, a created task or a change of many tasks refreshes
+// the rows of the page query. It returns no component for a task the user can't see.
+func (ts *TaskServer) taskEventComponent(ctx context.Context, event entity.TaskEvent) (templ.Component, error) {
+	if event.Type == entity.TaskCreated || event.Type == entity.TasksChanged {
+		return view.TaskRowsRefreshEvent(), nil
+	}
+
+	count, err := ts.storage.TaskCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.Type == entity.TaskDeleted {
+		return view.TaskDeletedEvent(event.ID, count), nil
+	}
+
+	task, ok, err := ts.storage.Task(ctx, event.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok { // deleted in the meantime, its delete event follows
+		return nil, nil
+	}
+
+	dependencies, err := ts.storage.Dependencies(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return view.TaskUpdatedEvent(task.Overview().WithDependencies(dependencies)), nil
+}
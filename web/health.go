@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dgf/go-ssr-x/log"
+)
+
+// probeTimeout limits every check of a probe.
+const probeTimeout = 2 * time.Second
+
+var errNotReady = errors.New("server is not ready, e.g. shutting down")
+
+type probeCheck func(ctx context.Context) error
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type probeResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// Health is the liveness probe, it checks the storage access.
+func (s *Server) Health(w http.ResponseWriter, r *http.Request) {
+	probe(w, r, map[string]probeCheck{
+		"storage": s.Storage.Ping,
+	})
+}
+
+// Readiness is the readiness probe, it fails while the server does not accept requests.
+func (s *Server) Readiness(w http.ResponseWriter, r *http.Request) {
+	probe(w, r, map[string]probeCheck{
+		"server": func(_ context.Context) error {
+			if !s.Ready() {
+				return errNotReady
+			}
+
+			return nil
+		},
+		"storage": s.Storage.Ping,
+	})
+}
+
+// probe responds the results of the checks, with 503 if a check fails.
+func probe(w http.ResponseWriter, r *http.Request, checks map[string]probeCheck) {
+	status := http.StatusOK
+	result := probeResult{Status: "ok", Checks: map[string]checkResult{}}
+
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+		start := time.Now()
+		err := check(ctx)
+		cancel()
+
+		checked := checkResult{Status: "ok", Duration: time.Since(start).String()}
+		if err != nil {
+			log.InfoContext(r.Context(), "probe check failed", "check", name, "error", err.Error())
+			checked.Status = "failed"
+			checked.Error = err.Error()
+			result.Status = "failed"
+			status = http.StatusServiceUnavailable
+		}
+		result.Checks[name] = checked
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.ErrorContext(r.Context(), "probe encoding failed", err)
+	}
+}
@@ -2,10 +2,14 @@
 package web
 
 import (
+	"cmp"
+	"context"
 	"embed"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/a-h/templ"
@@ -13,17 +17,37 @@ import (
 	"github.com/dgf/go-ssr-x/entity"
 	"github.com/dgf/go-ssr-x/locale"
 	"github.com/dgf/go-ssr-x/log"
+	"github.com/dgf/go-ssr-x/metrics"
 	"github.com/dgf/go-ssr-x/web/view"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/text/language"
 )
 
 //go:embed assets/*
 var assets embed.FS
 
+const (
+	DefaultDrainTimeout = 15 * time.Second // limits the wait for active requests on shutdown
+	DefaultReadTimeout  = 17 * time.Second
+	DefaultWriteTimeout = 13 * time.Second
+	DefaultIdleTimeout  = 37 * time.Second
+)
+
 type Server struct {
-	Addr    string
-	Storage entity.Storage
-	mux     *http.ServeMux
+	Addr         string
+	Storage      entity.Storage
+	SessionKey   []byte        // signs the session cookies, a random key invalidates them on restart
+	DrainTimeout time.Duration // DefaultDrainTimeout if zero
+	ReadTimeout  time.Duration // DefaultReadTimeout if zero
+	WriteTimeout time.Duration // DefaultWriteTimeout if zero
+	IdleTimeout  time.Duration // DefaultIdleTimeout if zero
+	AdminToken   string        // enables the admin endpoints for bearer requests with the token
+	mux          *http.ServeMux
+	storage      entity.Storage // instrumented storage
+	httpMetrics  *metrics.HTTP
+	sessions     sessions
+	httpServer   atomic.Pointer[http.Server]
+	ready        atomic.Bool
 }
 
 func NewServer() *Server {
@@ -36,24 +60,48 @@ func NewServer() *Server {
 	return &Server{mux: m}
 }
 
-func (s *Server) Serve() error {
+// Serve handles requests until the context is done, then it shuts down and drains the active requests.
+func (s *Server) Serve(ctx context.Context) error {
 	if len(s.Addr) == 0 {
 		return errors.New("missing addr config")
 	}
 
 	if s.Storage == nil {
-		// TODO test access too?
 		return errors.New("requires an active storage reference")
 	}
 
-	return s.serve()
+	err := s.Storage.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("storage access failed: %w", err)
+	}
+
+	return s.serve(ctx)
+}
+
+// Ready reports whether the server accepts requests, it is not ready before serving and while draining.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// Shutdown stops accepting requests and waits for the active requests until the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.ready.Store(false)
+
+	server := s.httpServer.Load()
+	if server == nil {
+		return nil
+	}
+
+	log.Info("server shutdown, draining active requests")
+
+	return server.Shutdown(ctx)
 }
 
 func acceptLanguageOrDefault(r *http.Request) language.Tag {
 	accept := r.Header.Get("Accept-Language")
 	tags, _, err := language.ParseAcceptLanguage(accept)
 	if err != nil {
-		log.Info("accept language header parse failed", "header", accept)
+		log.InfoContext(r.Context(), "accept language header parse failed", "header", accept)
 
 		return language.English
 	}
@@ -65,24 +113,54 @@ func acceptLanguageOrDefault(r *http.Request) language.Tag {
 	return tags[0]
 }
 
+// localeContext returns the request context with the accepted locale, also as log argument.
+func localeContext(r *http.Request) context.Context {
+	lang := acceptLanguageOrDefault(r)
+
+	return log.With(locale.WithLocale(r.Context(), lang), "locale", lang.String())
+}
+
+// userContext returns the context scoped to the user, also as log argument.
+func userContext(ctx context.Context, user entity.User) context.Context {
+	return log.With(entity.WithUser(ctx, user), "user", user.Name)
+}
+
+// handle registers the instrumented handler of the pattern.
+func (s *Server) handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.httpMetrics.Handler(pattern, handler))
+}
+
+// route renders the handler component for signed-in users, see publicRoute.
 func (s *Server) route(pattern string, handler func(http.ResponseWriter, *http.Request) templ.Component) {
-	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+	s.handle(pattern, s.requireUser(render(handler)))
+}
+
+// publicRoute renders the handler component, as page for non htmx requests,
+// a nil component means the handler has written the response, e.g. a redirect.
+func (s *Server) publicRoute(pattern string, handler func(http.ResponseWriter, *http.Request) templ.Component) {
+	s.handle(pattern, render(handler))
+}
+
+func render(handler func(http.ResponseWriter, *http.Request) templ.Component) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
 		w.Header().Add("Content-Type", "text/html; charset=utf-8")
 
-		lang := acceptLanguageOrDefault(r)
-		ctx := locale.WithLocale(r.Context(), lang)
-
+		ctx := localeContext(r)
 		component := handler(w, r.WithContext(ctx))
+		if component == nil {
+			return
+		}
+
 		if r.Header.Get("HX-Request") != "true" {
 			component = view.Page(component)
 		}
 
 		err := component.Render(ctx, w)
 		if err != nil {
-			log.Error("component rendering failed", err)
+			log.ErrorContext(ctx, "component rendering failed", err)
 		}
-	})
+	}
 }
 
 func panicRecovery(next http.Handler) http.Handler {
@@ -91,9 +169,9 @@ func panicRecovery(next http.Handler) http.Handler {
 			if err := recover(); err != nil {
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				if e, ok := err.(error); ok {
-					log.Error("panic recovery uncaught error", e)
+					log.ErrorContext(req.Context(), "panic recovery uncaught error", e)
 				} else {
-					log.Error("panic recovery uncaught error", fmt.Errorf("%v", err))
+					log.ErrorContext(req.Context(), "panic recovery uncaught error", fmt.Errorf("%v", err))
 				}
 			}
 		}()
@@ -101,17 +179,128 @@ func panicRecovery(next http.Handler) http.Handler {
 	})
 }
 
-func (s *Server) serve() error {
-	taskServer := NewTaskServer(s.Storage)
+// listenTaskEvents restarts the listening of the event source after failures until the context is done.
+func listenTaskEvents(ctx context.Context, source entity.TaskEventSource, hub *entity.Hub) {
+	const retryDelay = 5 * time.Second
+
+	for ctx.Err() == nil {
+		err := source.ListenTaskEvents(ctx, hub)
+		if err != nil && ctx.Err() == nil {
+			log.Error("task events listening failed, retry in "+retryDelay.String(), err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
+
+// recurTasks generates the next occurrences of the finished and overdue recurring tasks of all users, at the start and
+// then hourly until the context is done.
+func recurTasks(ctx context.Context, storage entity.TaskRecurrenceStorage) {
+	const interval = time.Hour
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		occurrences, err := storage.RecurTasks(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Error("task recurrence failed, retry in "+interval.String(), err)
+		} else if len(occurrences) > 0 {
+			log.Info("recurring tasks passed on", "count", len(occurrences))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) drainTimeout() time.Duration {
+	if s.DrainTimeout > 0 {
+		return s.DrainTimeout
+	}
+
+	return DefaultDrainTimeout
+}
+
+func (s *Server) serve(ctx context.Context) error {
+	if len(s.SessionKey) == 0 {
+		key, err := newSessionKey()
+		if err != nil {
+			return fmt.Errorf("session key generation failed: %w", err)
+		}
+
+		log.Warn("signing sessions with a random key, the sessions will be lost when restarting")
+		s.SessionKey = key
+	}
+	s.sessions = sessions{key: s.SessionKey}
+
+	registry := metrics.NewRegistry()
+	s.httpMetrics = metrics.NewHTTP(registry)
+	s.storage = metrics.NewStorage(s.Storage, registry)
+	if source, ok := s.Storage.(metrics.CollectorSource); ok {
+		registry.MustRegister(source.MetricsCollector())
+	}
+	s.mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	hub := entity.NewHub()
+	storage := entity.Storage(entity.NewPublishingStorage(s.storage, hub))
+	if source, ok := s.Storage.(entity.TaskEventSource); ok {
+		storage = s.storage
+		go listenTaskEvents(ctx, source, hub)
+	}
+	go recurTasks(ctx, storage)
+
+	taskServer := NewTaskServer(storage, hub)
+
+	s.handle("GET /healthz", s.Health)
+	s.handle("GET /readyz", s.Readiness)
+
+	if s.AdminToken != "" {
+		s.admin("GET /admin/log/level", s.APILogLevel)
+		s.admin("PUT /admin/log/level", s.APIUpdateLogLevel)
+	}
+
+	s.publicRoute("GET /login", s.LoginForm)
+	s.publicRoute("POST /login", s.Login)
+	s.publicRoute("POST /logout", s.Logout)
 
 	s.route("GET /tasks/new", taskServer.TaskCreateForm)
 	s.route("GET /tasks/rows", taskServer.TaskRows)
+	s.handle("GET /tasks/events", s.requireUser(taskServer.TaskEvents))
 	s.route("GET /tasks", taskServer.TasksSection)
 	s.route("POST /tasks", taskServer.CreateTask)
+	s.route("POST /tasks/preview", taskServer.PreviewDescription)
 	s.route("GET /tasks/{id}", taskServer.ShowTask)
 	s.route("GET /tasks/{id}/edit", taskServer.EditTask)
+	s.route("GET /tasks/{id}/subtasks", taskServer.TaskTree)
+	s.route("GET /tasks/{id}/dependencies", taskServer.TaskDependencies)
+	s.route("POST /tasks/{id}/dependencies", taskServer.AddTaskDependency)
+	s.route("DELETE /tasks/{id}/dependencies/{blocker}", taskServer.RemoveTaskDependency)
 	s.route("DELETE /tasks/{id}", taskServer.DeleteTask)
 	s.route("PUT /tasks/{id}", taskServer.UpdateTask)
+	s.route("PUT /tasks/{id}/status", taskServer.UpdateTaskStatus)
+	s.route("PUT /tasks/{id}/checklist/{item}", taskServer.ToggleChecklistItem)
+
+	s.api("GET "+apiPrefix+"/tasks", taskServer.APITasks)
+	s.api("POST "+apiPrefix+"/tasks", taskServer.APICreateTask)
+	s.api("GET "+apiPrefix+"/tasks/export", taskServer.APIExportTasks)
+	s.api("POST "+apiPrefix+"/tasks/import", taskServer.APIImportTasks)
+	s.api("GET "+apiPrefix+"/tasks/plan", taskServer.APIWorkPlan)
+	s.api("GET "+apiPrefix+"/tasks/{id}", taskServer.APITask)
+	s.api("PUT "+apiPrefix+"/tasks/{id}", taskServer.APIUpdateTask)
+	s.api("DELETE "+apiPrefix+"/tasks/{id}", taskServer.APIDeleteTask)
+	s.api("PUT "+apiPrefix+"/tasks/{id}/status", taskServer.APIUpdateTaskStatus)
+	s.api("GET "+apiPrefix+"/tasks/{id}/dependencies", taskServer.APITaskDependencies)
+	s.api("PUT "+apiPrefix+"/tasks/{id}/dependencies/{blockerId}", taskServer.APIAddTaskDependency)
+	s.api("DELETE "+apiPrefix+"/tasks/{id}/dependencies/{blockerId}", taskServer.APIRemoveTaskDependency)
+	s.api(apiPrefix+"/", func(_ http.ResponseWriter, r *http.Request) (int, any) {
+		return apiError(r, http.StatusNotFound, "not_found_path", map[string]string{"method": r.Method, "path": r.URL.Path})
+	})
 
 	s.route("/", func(w http.ResponseWriter, r *http.Request) templ.Component {
 		if r.URL.Path == "/" {
@@ -127,11 +316,41 @@ func (s *Server) serve() error {
 		return view.ClientError("not_found_path", map[string]string{"method": r.Method, "path": r.URL.Path})
 	})
 
-	return (&http.Server{
+	server := &http.Server{
 		Addr:         s.Addr,
-		Handler:      panicRecovery(s.mux),
-		WriteTimeout: 13 * time.Second,
-		ReadTimeout:  17 * time.Second,
-		IdleTimeout:  37 * time.Second,
-	}).ListenAndServe()
+		Handler:      accessLogging(panicRecovery(s.mux)),
+		WriteTimeout: cmp.Or(s.WriteTimeout, DefaultWriteTimeout),
+		ReadTimeout:  cmp.Or(s.ReadTimeout, DefaultReadTimeout),
+		IdleTimeout:  cmp.Or(s.IdleTimeout, DefaultIdleTimeout),
+	}
+	server.RegisterOnShutdown(hub.Close) // ends the event streams, they never become idle
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.httpServer.Store(server)
+	s.ready.Store(true)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		s.ready.Store(false)
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.drainTimeout())
+	defer cancel()
+
+	return s.Shutdown(drainCtx)
 }
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sessionCookieName = "session"
+	sessionMaxAge     = 7 * 24 * time.Hour
+	sessionKeySize    = 32
+)
+
+// sessions signs the session cookies, a value is the user ID and the expiry time with their HMAC-SHA256.
+type sessions struct {
+	key []byte
+}
+
+func newSessionKey() ([]byte, error) {
+	key := make([]byte, sessionKeySize)
+	_, err := rand.Read(key)
+
+	return key, err
+}
+
+func (s sessions) cookie(r *http.Request, userID uuid.UUID, now time.Time) *http.Cookie {
+	expires := now.Add(sessionMaxAge)
+	payload := userID.String() + "." + strconv.FormatInt(expires.Unix(), 10)
+
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    payload + "." + s.sign(payload),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (s sessions) expiredCookie(r *http.Request) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// userID returns the user ID of a valid and unexpired session cookie.
+func (s sessions) userID(r *http.Request, now time.Time) (uuid.UUID, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	payload, signature, ok := cutLast(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return uuid.Nil, false
+	}
+
+	id, expires, ok := strings.Cut(payload, ".")
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.After(time.Unix(unix, 0)) {
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(id)
+
+	return userID, err == nil
+}
+
+func (s sessions) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i == -1 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
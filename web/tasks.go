@@ -1,30 +1,56 @@
 package web
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/a-h/templ"
 	"github.com/dgf/go-ssr-x/entity"
 	"github.com/dgf/go-ssr-x/log"
+	"github.com/dgf/go-ssr-x/markdown"
 	"github.com/dgf/go-ssr-x/web/view"
 	"github.com/google/uuid"
 )
 
 type TaskServer struct {
 	storage entity.Storage
+	hub     *entity.Hub
 }
 
-func NewTaskServer(storage entity.Storage) *TaskServer {
-	return &TaskServer{storage: storage}
+func NewTaskServer(storage entity.Storage, hub *entity.Hub) *TaskServer {
+	return &TaskServer{storage: storage, hub: hub}
 }
 
-func (ts *TaskServer) TaskCreateForm(_ http.ResponseWriter, _ *http.Request) templ.Component {
-	return view.TaskCreateForm()
+// TaskCreateForm adds a subtask of the parent query param.
+func (ts *TaskServer) TaskCreateForm(w http.ResponseWriter, r *http.Request) templ.Component {
+	qParent := r.URL.Query().Get("parent")
+	if qParent == "" {
+		return view.TaskCreateForm(entity.Task{})
+	}
+
+	parentID, err := uuid.Parse(qParent)
+	if err != nil {
+		return clientError(w, r, http.StatusBadRequest, "bad_request_query_param", map[string]string{"param": "parent", "value": qParent})
+	}
+
+	parent, ok, err := ts.storage.Task(r.Context(), parentID)
+	if err != nil {
+		log.ErrorContext(r.Context(), "parent task access failed", err)
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+	if !ok {
+		return clientError(w, r, http.StatusNotFound, "not_found_task", map[string]string{"id": qParent})
+	}
+
+	return view.TaskCreateForm(parent)
 }
 
 func (ts *TaskServer) TaskRows(w http.ResponseWriter, r *http.Request) templ.Component {
@@ -35,16 +61,18 @@ func (ts *TaskServer) TaskRows(w http.ResponseWriter, r *http.Request) templ.Com
 		RawQuery: taskQuery2QueryParams(query),
 	}
 
-	w.Header().Add("HX-Push-Url", pushURL.String())
+	if r.Header.Get("HX-Trigger") != view.TaskRowsRefreshID { // a live refresh keeps the history
+		w.Header().Add("HX-Push-Url", pushURL.String())
+	}
 
 	page, err := ts.storage.Tasks(r.Context(), query)
 	if err != nil {
-		log.Error("task rows access failed", err)
+		log.ErrorContext(r.Context(), "task rows access failed", err)
 
 		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 	}
 
-	return view.TaskPageRows(page)
+	return view.TaskPageRows(page, entity.SearchTerms(query.Search))
 }
 
 func (ts *TaskServer) TasksSection(w http.ResponseWriter, r *http.Request) templ.Component {
@@ -52,7 +80,7 @@ func (ts *TaskServer) TasksSection(w http.ResponseWriter, r *http.Request) templ
 
 	page, err := ts.storage.Tasks(r.Context(), query)
 	if err != nil {
-		log.Error("tasks section access failed", err)
+		log.ErrorContext(r.Context(), "tasks section access failed", err)
 
 		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 	}
@@ -63,30 +91,41 @@ func (ts *TaskServer) TasksSection(w http.ResponseWriter, r *http.Request) templ
 func (ts *TaskServer) CreateTask(w http.ResponseWriter, r *http.Request) templ.Component {
 	err := r.ParseForm()
 	if err != nil {
-		log.Warn(fmt.Sprintf("task create form parsing failed: %v", err))
+		log.WarnContext(r.Context(), fmt.Sprintf("task create form parsing failed: %v", err))
 
 		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 	}
 
-	id, err := ts.storage.AddTask(r.Context(), form2TaskData(r))
+	data, err := form2TaskData(r)
 	if err != nil {
-		log.Error("task creation failed", err)
+		return badFormParam(w, r, err)
+	}
+
+	id, err := ts.storage.AddTask(r.Context(), data)
+	if errors.Is(err, entity.ErrInvalidParent) {
+		return badFormParam(w, r, fieldError{field: "parent", value: data.ParentID.String()})
+	} else if err != nil {
+		log.ErrorContext(r.Context(), "task creation failed", err)
 		messageData := map[string]string{"message": err.Error()}
 
 		return clientError(w, r, http.StatusInternalServerError, "database_error", messageData)
 	}
 
+	if data.ParentID != uuid.Nil {
+		return ts.subtaskCreated(w, r, id, data.ParentID)
+	}
+
 	query := entity.TaskQuery{
 		Page:   1,
 		Size:   entity.TaskPageDefaultSize,
 		Sort:   entity.TaskSortDefault,
 		Order:  entity.AscendingOrder,
-		Filter: "",
+		Search: "",
 	}
 
 	page, err := ts.storage.Tasks(r.Context(), query)
 	if err != nil {
-		log.Error("task listing failed", err)
+		log.ErrorContext(r.Context(), "task listing failed", err)
 
 		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 	}
@@ -106,15 +145,108 @@ func (ts *TaskServer) ShowTask(w http.ResponseWriter, r *http.Request) templ.Com
 	return ts.handleTask(w, r, view.TaskDetails)
 }
 
+// TaskTree shows the parents and the subtasks of the task details.
+func (ts *TaskServer) TaskTree(w http.ResponseWriter, r *http.Request) templ.Component {
+	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
+		ancestors, err := ts.storage.Ancestors(r.Context(), task.ID)
+		if err != nil {
+			log.ErrorContext(r.Context(), "task ancestors access failed", err)
+
+			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		subtasks, err := ts.storage.Subtasks(r.Context(), task.ID)
+		if err != nil {
+			log.ErrorContext(r.Context(), "subtasks access failed", err)
+
+			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return view.TaskTree(ancestors, subtasks)
+	})
+}
+
+// TaskDependencies shows the blockers and the blocked tasks of the task details.
+func (ts *TaskServer) TaskDependencies(w http.ResponseWriter, r *http.Request) templ.Component {
+	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
+		return ts.taskDependencies(w, r, task)
+	})
+}
+
+// AddTaskDependency blocks the task by the blocker form param.
+func (ts *TaskServer) AddTaskDependency(w http.ResponseWriter, r *http.Request) templ.Component {
+	err := r.ParseForm()
+	if err != nil {
+		log.WarnContext(r.Context(), fmt.Sprintf("task dependency form parsing failed: %v", err))
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	value := r.FormValue("blocker")
+	blockerID, err := uuid.Parse(value)
+	if err != nil {
+		return badFormParam(w, r, fieldError{field: "blocker", value: value})
+	}
+
+	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
+		err := ts.storage.AddDependency(r.Context(), task.ID, blockerID)
+		if errors.Is(err, entity.ErrDependencyCycle) {
+			return clientError(w, r, http.StatusConflict, "conflict_task_dependency", map[string]string{"id": task.ID.String(), "blocker": value})
+		} else if errors.Is(err, entity.ErrNotFound) {
+			return clientError(w, r, http.StatusNotFound, "not_found_task", map[string]string{"id": value})
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("task dependency creation failed: %v", err))
+
+			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return ts.taskDependencies(w, r, task)
+	})
+}
+
+// RemoveTaskDependency unblocks the task of the blocker path param.
+func (ts *TaskServer) RemoveTaskDependency(w http.ResponseWriter, r *http.Request) templ.Component {
+	pBlocker := r.PathValue("blocker")
+	blockerID, err := uuid.Parse(pBlocker)
+	if err != nil {
+		return clientError(w, r, http.StatusBadRequest, "bad_request_path_param", map[string]string{"param": "blocker", "value": pBlocker})
+	}
+
+	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
+		err := ts.storage.RemoveDependency(r.Context(), task.ID, blockerID)
+		if errors.Is(err, entity.ErrNotFound) {
+			return clientError(w, r, http.StatusNotFound, "not_found_task_dependency", map[string]string{"id": task.ID.String(), "blocker": pBlocker})
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("task dependency deletion failed: %v", err))
+
+			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+
+		return ts.taskDependencies(w, r, task)
+	})
+}
+
 func (ts *TaskServer) EditTask(w http.ResponseWriter, r *http.Request) templ.Component {
 	return ts.handleTask(w, r, view.TaskEditForm)
 }
 
+// PreviewDescription renders the Markdown description of the create and edit forms.
+func (ts *TaskServer) PreviewDescription(w http.ResponseWriter, r *http.Request) templ.Component {
+	err := r.ParseForm()
+	if err != nil {
+		log.WarnContext(r.Context(), fmt.Sprintf("task preview form parsing failed: %v", err))
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	return view.TaskDescriptionPreview(r.FormValue("description"))
+}
+
 func (ts *TaskServer) DeleteTask(w http.ResponseWriter, r *http.Request) templ.Component {
 	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
 		err := ts.storage.DeleteTask(r.Context(), task.ID)
 		if err != nil {
-			log.Warn(fmt.Sprintf("task deletion failed: %v", err))
+			log.WarnContext(r.Context(), fmt.Sprintf("task deletion failed: %v", err))
 
 			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 		}
@@ -128,15 +260,23 @@ func (ts *TaskServer) DeleteTask(w http.ResponseWriter, r *http.Request) templ.C
 func (ts *TaskServer) UpdateTask(w http.ResponseWriter, r *http.Request) templ.Component {
 	err := r.ParseForm()
 	if err != nil {
-		log.Warn(fmt.Sprintf("task update form parsing failed: %v", err))
+		log.WarnContext(r.Context(), fmt.Sprintf("task update form parsing failed: %v", err))
 
 		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 	}
 
+	data, err := form2TaskData(r)
+	if err != nil {
+		return badFormParam(w, r, err)
+	}
+
 	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
-		updated, ok, err := ts.storage.UpdateTask(r.Context(), task.ID, form2TaskData(r))
-		if err != nil {
-			log.Warn(fmt.Sprintf("task update failed: %v ", err))
+		updated, ok, err := ts.storage.UpdateTask(r.Context(), task.ID, data)
+		var conflict entity.ConflictError
+		if errors.As(err, &conflict) {
+			return taskConflict(w, r, conflict.Task, data)
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("task update failed: %v ", err))
 
 			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 		}
@@ -156,6 +296,170 @@ func (ts *TaskServer) UpdateTask(w http.ResponseWriter, r *http.Request) templ.C
 	})
 }
 
+func (ts *TaskServer) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) templ.Component {
+	err := r.ParseForm()
+	if err != nil {
+		log.WarnContext(r.Context(), fmt.Sprintf("task status form parsing failed: %v", err))
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	value := r.FormValue("status")
+	status, ok := entity.ParseTaskStatus(value)
+	if !ok {
+		badData := map[string]string{"param": "status", "value": value}
+
+		return clientError(w, r, http.StatusBadRequest, "bad_request_form_param", badData)
+	}
+
+	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
+		updated, ok, err := ts.storage.UpdateTaskStatus(r.Context(), task.ID, status)
+		if ok && err == nil {
+			updated, err = ts.passRecurrence(r.Context(), updated)
+		}
+		var transition entity.TransitionError
+		if errors.As(err, &transition) {
+			conflictData := map[string]string{
+				"from": view.TaskStatusLabel(r.Context(), transition.Task.Status),
+				"to":   view.TaskStatusLabel(r.Context(), status),
+			}
+
+			return clientError(w, r, http.StatusConflict, "conflict_task_status", conflictData)
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("task status update failed: %v ", err))
+
+			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+		if !ok { // e.g. delete while user is updating
+			return clientError(w, r, http.StatusConflict, "conflict_task_update", nil)
+		}
+
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			statusData := map[string]string{"id": updated.ID.String(), "status": view.TaskStatusLabel(ctx, status)}
+			err := view.SuccessNotify("ok_task_status_updated", statusData).Render(ctx, w)
+			if err != nil {
+				return err
+			}
+
+			return view.TaskDetails(updated).Render(ctx, w)
+		})
+	})
+}
+
+// ToggleChecklistItem checks or unchecks the numbered task list item of the description.
+func (ts *TaskServer) ToggleChecklistItem(w http.ResponseWriter, r *http.Request) templ.Component {
+	err := r.ParseForm()
+	if err != nil {
+		log.WarnContext(r.Context(), fmt.Sprintf("task checklist form parsing failed: %v", err))
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	pItem := r.PathValue("item")
+	item, err := strconv.Atoi(pItem)
+	if err != nil || item < 0 {
+		return clientError(w, r, http.StatusBadRequest, "bad_request_path_param", map[string]string{"param": "item", "value": pItem})
+	}
+
+	version := int64(0)
+	if value := r.FormValue("version"); value != "" {
+		version, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return badFormParam(w, r, fieldError{field: "version", value: value})
+		}
+	}
+
+	return ts.handleTask(w, r, func(task entity.Task) templ.Component {
+		description, ok := markdown.ToggleChecklistItem(task.Description, item)
+		if !ok {
+			return clientError(w, r, http.StatusNotFound, "not_found_checklist_item", map[string]string{"item": pItem})
+		}
+
+		data := entity.TaskData{
+			DueDate:     task.DueDate,
+			Subject:     task.Subject,
+			Description: description,
+			Tags:        task.Tags,
+			Version:     version,
+			Recurrence:  task.Recurrence,
+		}
+
+		updated, ok, err := ts.storage.UpdateTask(r.Context(), task.ID, data)
+		var conflict entity.ConflictError
+		if errors.As(err, &conflict) {
+			return taskConflict(w, r, conflict.Task, data)
+		} else if err != nil {
+			log.WarnContext(r.Context(), fmt.Sprintf("task checklist update failed: %v ", err))
+
+			return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+		}
+		if !ok {
+			return clientError(w, r, http.StatusConflict, "conflict_task_update", nil)
+		}
+
+		return view.TaskDetails(updated)
+	})
+}
+
+// taskDependencies offers the unfinished tasks of the work plan as blockers.
+func (ts *TaskServer) taskDependencies(w http.ResponseWriter, r *http.Request, task entity.Task) templ.Component {
+	dependencies, err := ts.storage.Dependencies(r.Context(), task.ID)
+	if err != nil {
+		log.ErrorContext(r.Context(), "task dependencies access failed", err)
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	plan, err := ts.storage.WorkPlan(r.Context())
+	if err != nil {
+		log.ErrorContext(r.Context(), "work plan access failed", err)
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	return view.TaskDependencies(task, dependencies, plan)
+}
+
+// passRecurrence generates the next occurrence of a finished or overdue recurring task and returns the passed task.
+func (ts *TaskServer) passRecurrence(ctx context.Context, task entity.Task) (entity.Task, error) {
+	if !task.Recurs(time.Now()) {
+		return task, nil
+	}
+
+	_, recurred, err := ts.storage.RecurTask(ctx, task.ID, time.Now())
+	if err != nil || !recurred {
+		return task, err
+	}
+
+	passed, ok, err := ts.storage.Task(ctx, task.ID)
+	if err != nil || !ok {
+		return task, err
+	}
+
+	return passed, nil
+}
+
+// subtaskCreated shows the details of the parent.
+func (ts *TaskServer) subtaskCreated(w http.ResponseWriter, r *http.Request, id, parentID uuid.UUID) templ.Component {
+	parent, ok, err := ts.storage.Task(r.Context(), parentID)
+	if err != nil || !ok {
+		log.ErrorContext(r.Context(), "parent task access failed", errors.Join(err, fmt.Errorf("task %s found %t", parentID, ok)))
+
+		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	w.Header().Set("HX-Push-Url", "/tasks/"+parentID.String())
+
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		err := view.SuccessNotify("ok_task_created", map[string]string{"id": id.String()}).Render(ctx, w)
+		if err != nil {
+			return err
+		}
+
+		return view.TaskDetails(parent).Render(ctx, w)
+	})
+}
+
 type handlerFunc func(entity.Task) templ.Component
 
 func (ts *TaskServer) handleTask(w http.ResponseWriter, r *http.Request, handler handlerFunc) templ.Component {
@@ -169,7 +473,7 @@ func (ts *TaskServer) handleTask(w http.ResponseWriter, r *http.Request, handler
 
 	task, ok, err := ts.storage.Task(r.Context(), id)
 	if err != nil {
-		log.Error("task access failed", err)
+		log.ErrorContext(r.Context(), "task access failed", err)
 
 		return clientError(w, r, http.StatusInternalServerError, "internal_server_error", nil)
 	}
@@ -184,12 +488,41 @@ func (ts *TaskServer) handleTask(w http.ResponseWriter, r *http.Request, handler
 
 func queryParams2TaskQuery(query url.Values) entity.TaskQuery {
 	return entity.TaskQuery{
-		Page:   param2IntOrDefault(query, "page", 1),
-		Size:   param2IntOrDefault(query, "size", entity.TaskPageDefaultSize),
-		Sort:   entity.TaskSortOrDefault(query.Get("sort")),
-		Order:  entity.SortOrderOrDefault(query.Get("order")),
-		Filter: query.Get("subject"),
+		Page:     param2IntOrDefault(query, "page", 1),
+		Size:     param2IntOrDefault(query, "size", entity.TaskPageDefaultSize),
+		Sort:     entity.TaskSortOrDefault(query.Get("sort")),
+		Order:    entity.SortOrderOrDefault(query.Get("order")),
+		Search:   cmp.Or(query.Get("search"), query.Get("subject")), // the subject filter of older links
+		Statuses: params2TaskStatuses(query["status"]),
+		Tags:     params2Tags(query["tag"]),
+		Blocked:  query.Get("blocked") == "true",
+	}
+}
+
+func params2Tags(values []string) []string {
+	tags := []string{}
+	for _, value := range values {
+		parsed, err := entity.ParseTags(value)
+		if err != nil {
+			log.Info("tag query param parse failed", "value", value)
+
+			continue
+		}
+		tags = append(tags, parsed...)
 	}
+
+	return tags
+}
+
+func params2TaskStatuses(values []string) []entity.TaskStatus {
+	statuses := []entity.TaskStatus{}
+	for _, value := range values {
+		if status, ok := entity.ParseTaskStatus(value); ok {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses
 }
 
 func taskQuery2QueryParams(query entity.TaskQuery) string {
@@ -197,17 +530,66 @@ func taskQuery2QueryParams(query entity.TaskQuery) string {
 
 	values.Add("sort", query.Sort.String())
 	values.Add("order", query.Order.String())
-	values.Add("subject", query.Filter)
+	values.Add("search", query.Search)
+	for _, status := range query.Statuses {
+		values.Add("status", status.String())
+	}
+	for _, tag := range query.Tags {
+		values.Add("tag", tag)
+	}
+	if query.Blocked {
+		values.Add("blocked", "true")
+	}
 	values.Add("page", strconv.Itoa(query.Page))
 	values.Add("size", strconv.Itoa(query.Size))
 
 	return values.Encode()
 }
 
-func form2TaskData(r *http.Request) entity.TaskData {
+func form2TaskData(r *http.Request) (entity.TaskData, error) {
+	tags, err := entity.ParseTags(r.FormValue("tags"))
+	if err != nil {
+		return entity.TaskData{}, fieldError{field: "tags", value: r.FormValue("tags")}
+	}
+
+	version := int64(0)
+	if value := r.FormValue("version"); value != "" {
+		version, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return entity.TaskData{}, fieldError{field: "version", value: value}
+		}
+	}
+
+	parentID := uuid.Nil
+	if value := r.FormValue("parent"); value != "" {
+		parentID, err = uuid.Parse(value)
+		if err != nil {
+			return entity.TaskData{}, fieldError{field: "parent", value: value}
+		}
+	}
+
+	recurrence, err := entity.ParseRecurrence(r.FormValue("recurrence"))
+	if err != nil {
+		return entity.TaskData{}, fieldError{field: "recurrence", value: r.FormValue("recurrence")}
+	}
+
 	return entity.TaskData{
 		DueDate:     parseDate(r.FormValue("dueDate")),
 		Subject:     r.FormValue("subject"),
 		Description: r.FormValue("description"),
+		Tags:        tags,
+		Version:     version,
+		ParentID:    parentID,
+		Recurrence:  recurrence,
+	}, nil
+}
+
+func badFormParam(w http.ResponseWriter, r *http.Request, err error) templ.Component {
+	badData := map[string]string{"param": "form", "value": err.Error()}
+	var fe fieldError
+	if errors.As(err, &fe) {
+		badData = map[string]string{"param": fe.field, "value": fe.value}
 	}
+
+	return clientError(w, r, http.StatusBadRequest, "bad_request_form_param", badData)
 }
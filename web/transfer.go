@@ -0,0 +1,109 @@
+package web
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dgf/go-ssr-x/log"
+	"github.com/dgf/go-ssr-x/transfer"
+)
+
+// apiMaxImportBytes limits the body of an import.
+const apiMaxImportBytes = 32 << 20
+
+// transferFiles are the content type and the file name of each transfer format.
+var transferFiles = map[string]struct {
+	contentType string
+	name        string
+}{
+	transfer.FormatJSONL:    {"application/x-ndjson", "tasks.jsonl"},
+	transfer.FormatCSV:      {"text/csv; charset=utf-8", "tasks.csv"},
+	transfer.FormatMarkdown: {"application/x-tar", "tasks.tar"},
+}
+
+// APIExportTasks streams all tasks with all fields as JSON lines, CSV or a tar archive of Markdown files.
+func (ts *TaskServer) APIExportTasks(w http.ResponseWriter, r *http.Request) (int, any) {
+	format, ok := transferFormat(r)
+	if !ok {
+		return badTransferFormat(r, format)
+	}
+
+	encoder, err := transfer.NewEncoder(format, w)
+	if err != nil {
+		return badTransferFormat(r, format)
+	}
+
+	err = http.NewResponseController(w).SetWriteDeadline(time.Time{}) // a large export outlasts the server write timeout
+	if err != nil {
+		log.ErrorContext(r.Context(), "API task export write deadline reset failed", err)
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	w.Header().Set("Content-Type", transferFiles[format].contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+transferFiles[format].name+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	count, err := transfer.Export(r.Context(), ts.storage, encoder)
+	if err != nil {
+		log.ErrorContext(r.Context(), "API task export failed after "+strconv.Itoa(count)+" tasks", err) // the status is sent
+	}
+
+	return 0, nil
+}
+
+// APIImportTasks upserts the tasks of the body by ID and reports the invalid rows, the dry run saves nothing.
+func (ts *TaskServer) APIImportTasks(w http.ResponseWriter, r *http.Request) (int, any) {
+	format, ok := transferFormat(r)
+	if !ok {
+		return badTransferFormat(r, format)
+	}
+
+	dryRunParam := r.URL.Query().Get("dryRun")
+	dryRun, err := strconv.ParseBool(cmp.Or(dryRunParam, "false"))
+	if err != nil {
+		return apiError(r, http.StatusBadRequest, "bad_request_query_param", map[string]string{"param": "dryRun", "value": dryRunParam})
+	}
+
+	decoder, err := transfer.NewDecoder(format, http.MaxBytesReader(w, r.Body, apiMaxImportBytes))
+	if err != nil {
+		return badTransferFormat(r, format)
+	}
+
+	err = http.NewResponseController(w).SetReadDeadline(time.Time{}) // a large import outlasts the server read timeout
+	if err != nil {
+		log.ErrorContext(r.Context(), "API task import read deadline reset failed", err)
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	report, err := transfer.Import(r.Context(), ts.storage, decoder, dryRun)
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes):
+		return apiError(r, http.StatusRequestEntityTooLarge, "content_too_large", map[string]string{"limit": strconv.FormatInt(maxBytes.Limit, 10)})
+	case errors.Is(err, transfer.ErrInvalidInput):
+		return apiBadRequest(r, err)
+	case err != nil:
+		log.ErrorContext(r.Context(), "API task import failed after "+strconv.Itoa(report.Rows)+" rows", err)
+
+		return apiError(r, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+
+	return http.StatusOK, report
+}
+
+// transferFormat returns the format query param, JSON lines by default.
+func transferFormat(r *http.Request) (string, bool) {
+	format := cmp.Or(r.URL.Query().Get("format"), transfer.FormatJSONL)
+
+	return format, slices.Contains(transfer.Formats, format)
+}
+
+func badTransferFormat(r *http.Request, format string) (int, any) {
+	return apiError(r, http.StatusBadRequest, "bad_request_query_param", map[string]string{"param": "format", "value": format})
+}
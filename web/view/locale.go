@@ -2,8 +2,10 @@ package view
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/dgf/go-ssr-x/entity"
 	"github.com/dgf/go-ssr-x/locale"
 )
 
@@ -22,3 +24,12 @@ func translate(ctx context.Context, messageID string) string {
 func translateData(ctx context.Context, messageID string, data map[string]string) string {
 	return locale.TranslateData(ctx, messageID, data)
 }
+
+// TaskStatusLabel translates the status, e.g. in-progress by message task_status_in_progress.
+func TaskStatusLabel(ctx context.Context, status entity.TaskStatus) string {
+	return translate(ctx, "task_status_"+strings.ReplaceAll(status.String(), "-", "_"))
+}
+
+func taskStatusAction(ctx context.Context, status entity.TaskStatus) string {
+	return translate(ctx, "task_status_to_"+strings.ReplaceAll(status.String(), "-", "_"))
+}
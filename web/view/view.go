@@ -1,15 +1,18 @@
 package view
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/a-h/templ"
-	"github.com/dgf/go-ssr-x/log"
-	"github.com/yuin/goldmark"
+	"github.com/dgf/go-ssr-x/entity"
+	"github.com/dgf/go-ssr-x/markdown"
+	"github.com/google/uuid"
 )
 
 func date(d time.Time) string {
@@ -20,18 +23,204 @@ func date(d time.Time) string {
 	return ""
 }
 
-func markdown(md string) templ.Component {
-	var buf bytes.Buffer
-	err := goldmark.Convert([]byte(md), &buf)
-	if err != nil {
-		log.Warn(fmt.Sprintf("failed to convert markdown to HTML: %v", err))
+// TaskRowsRefreshID identifies the element that reloads the task rows of a live change.
+const TaskRowsRefreshID = "task-rows-refresh"
 
-		return templ.NopComponent
+// taskRowID prefixes the ID, an element ID has to start with a letter to be selectable.
+func taskRowID(id uuid.UUID) string {
+	return "task-" + id.String()
+}
+
+func tagList(tags []string) string {
+	return strings.Join(tags, ", ")
+}
+
+func tagURL(tag string) string {
+	return "/tasks?" + url.Values{"tag": {tag}}.Encode()
+}
+
+func statusColor(status entity.TaskStatus) string {
+	switch status {
+	case entity.TaskStatusInProgress:
+		return "bg-sky-300 dark:bg-sky-800"
+	case entity.TaskStatusDone:
+		return "bg-lime-400 dark:bg-lime-800"
+	case entity.TaskStatusCancelled:
+		return "bg-stone-400 line-through dark:bg-stone-600"
 	}
 
-	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
-		_, err := io.WriteString(w, buf.String())
+	return "bg-yellow-300 dark:bg-yellow-700"
+}
+
+func statusOptions(ctx context.Context) []Option {
+	options := []Option{{value: "", label: translate(ctx, "task_status_all")}}
+	for _, status := range entity.TaskStatuses() {
+		options = append(options, Option{value: status.String(), label: TaskStatusLabel(ctx, status)})
+	}
+
+	return options
+}
+
+func selectedStatus(statuses []entity.TaskStatus) string {
+	if len(statuses) == 1 {
+		return statuses[0].String()
+	}
+
+	return ""
+}
+
+func statusVals(status entity.TaskStatus) string {
+	return `{"status":"` + status.String() + `"}`
+}
+
+// versionVals sends the version of the checklist toggles to detect concurrent updates.
+func versionVals(version int64) string {
+	return `{"version":"` + strconv.FormatInt(version, 10) + `"}`
+}
+
+// textPart is a word or the text in between, it matches if the word starts with a search term.
+type textPart struct {
+	text    string
+	matches bool
+}
+
+// highlight splits the text into parts to mark the words matching the search terms.
+func highlight(text string, terms []string) []textPart {
+	parts := []textPart{}
+	add := func(part string, word bool) {
+		matches := word && slices.ContainsFunc(terms, func(term string) bool {
+			return strings.HasPrefix(strings.ToLower(part), term)
+		})
+		if last := len(parts) - 1; !matches && last >= 0 && !parts[last].matches {
+			parts[last].text += part
+
+			return
+		}
+		parts = append(parts, textPart{text: part, matches: matches})
+	}
+
+	start, word := 0, false
+	for i, r := range text {
+		isWord := entity.IsSearchRune(r)
+		if i > start && isWord != word {
+			add(text[start:i], word)
+			start = i
+		}
+		word = isWord
+	}
+
+	if start < len(text) {
+		add(text[start:], word)
+	}
+
+	return parts
+}
+
+// taskChange is a field of the current task that differs from the update data.
+type taskChange struct {
+	field   string
+	current string
+	yours   string
+}
+
+func taskChanges(ctx context.Context, task entity.Task, data entity.TaskData) []taskChange {
+	changes := []taskChange{}
+	add := func(messageID, current, yours string) {
+		if current != yours {
+			changes = append(changes, taskChange{field: translate(ctx, messageID), current: current, yours: yours})
+		}
+	}
+
+	add("task_subject", task.Subject, data.Subject)
+	add("task_due_date", localizeDate(ctx, task.DueDate), localizeDate(ctx, data.DueDate))
+	add("task_tags", tagList(task.Tags), tagList(data.Tags))
+	add("task_recurrence", task.Recurrence.String(), data.Recurrence.String())
+	add("task_description", normalizeText(task.Description), normalizeText(data.Description))
 
-		return err
+	return changes
+}
+
+// normalizeText trims the text and replaces the line breaks of form values.
+func normalizeText(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// markdownHTML renders the Markdown source, its checklist items toggle by the URL if it isn't empty.
+func markdownHTML(source, toggleURL string) templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		return markdown.Render(w, source, toggleURL)
 	})
 }
+
+// subtaskNode is a subtask with its children of the nested list.
+type subtaskNode struct {
+	task     entity.Subtask
+	children []subtaskNode
+}
+
+// nestSubtasks nests the depth-first ordered subtasks of the depth and returns the number of the consumed subtasks.
+func nestSubtasks(subtasks []entity.Subtask, depth int) ([]subtaskNode, int) {
+	nodes := []subtaskNode{}
+	next := 0
+	for next < len(subtasks) && subtasks[next].Depth == depth {
+		node := subtaskNode{task: subtasks[next]}
+		children, consumed := nestSubtasks(subtasks[next+1:], depth+1)
+		node.children = children
+		nodes = append(nodes, node)
+		next += 1 + consumed
+	}
+
+	return nodes, next
+}
+
+// subtaskTree returns the nested list of the subtasks, see entity.SortSubtasks.
+func subtaskTree(subtasks []entity.Subtask) []subtaskNode {
+	nodes, _ := nestSubtasks(subtasks, 1)
+
+	return nodes
+}
+
+// rootFirst returns the ancestors from the root to the direct parent.
+func rootFirst(ancestors []entity.TaskOverview) []entity.TaskOverview {
+	path := slices.Clone(ancestors)
+	slices.Reverse(path)
+
+	return path
+}
+
+// parentURL returns the details of the parent, the task list without parent.
+func parentURL(parent entity.Task) string {
+	if parent.ID == uuid.Nil {
+		return "/tasks"
+	}
+
+	return "/tasks/" + parent.ID.String()
+}
+
+// blockerOptions returns the planned tasks that could block the task, without the task itself and its blockers.
+func blockerOptions(ctx context.Context, task entity.Task, dependencies entity.TaskDependencies, plan []entity.TaskOverview) []Option {
+	options := []Option{{value: "", label: translate(ctx, "task_select_blocker")}}
+	for _, t := range plan {
+		blocks := slices.ContainsFunc(dependencies.BlockedBy, func(blocker entity.TaskOverview) bool { return blocker.ID == t.ID })
+		if t.ID != task.ID && !blocks {
+			options = append(options, Option{value: t.ID.String(), label: t.Subject})
+		}
+	}
+
+	return options
+}
+
+func blockedOptions(ctx context.Context) []Option {
+	return []Option{
+		{value: "", label: translate(ctx, "task_status_all")},
+		{value: "true", label: translate(ctx, "task_blocked")},
+	}
+}
+
+func selectedBlocked(blocked bool) string {
+	if blocked {
+		return "true"
+	}
+
+	return ""
+}